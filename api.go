@@ -28,6 +28,7 @@ from Spawn as you see fit.
 package hmetrics
 
 import (
+	"context"
 	"net/http"
 	"sync/atomic"
 	"time"
@@ -35,7 +36,7 @@ import (
 
 // EnvKeyEndpoint defines the name of the environment variable defining where
 // metrics should be posted to.  Its absence in environ inhibits hmetrics
-// startup.
+// startup, unless EnvKeyURL is present instead.
 const EnvKeyEndpoint = "HEROKU_METRICS_URL"
 
 // PackageHTTPVersion is the version string reported by default in the HTTP
@@ -116,10 +117,9 @@ func currentResetFailureBackoffTo() time.Duration {
 // Pass a non-zero time.Duration to modify.
 // Pass 0 to SetMetricsPostInterval to make no modification.
 // SetMetricsPostInterval returns the previous value.
-// SetMetricsPostInterval is safe to call at any time from any go-routine, but
-// the value is referenced once very shortly after starting the spawned
-// go-routine, so to modify this, you'll need to cancel the context of the
-// metrics poster and re-Spawn.
+// SetMetricsPostInterval is safe to call at any time from any go-routine; a
+// running poster will notice the change on its next tick and post at the new
+// interval thereafter.
 //
 // Do not use this unless you're very sure that Heroku will be happy:
 // their systems will be designed around an expectation of a certain
@@ -177,26 +177,32 @@ func GetHTTPUserAgent() string {
 // posting the metrics to Heroku's endpoint.  You'd typically only need this
 // when testing, to override the certificate authority trust store (or if you
 // don't normally want to trust the PKIX CA used by Heroku and need to
-// special-case it for them).
+// special-case it for them); for those cases, see also WithCAFile and the
+// other TLS options, which spare you building a client.
 // SetHTTPClient does not return anything.
 // Use GetHTTPClient to get the current value.
-// SetHTTPClient is safe to call at any time from any go-routine, but is only
-// referenced by the library when starting a loop, and the loop only exits
-// on context cancellation, so you'll need to cancel any previous poster and
-// spawn a new one.
+// SetHTTPClient is safe to call at any time from any go-routine; a running
+// poster will switch to the new client on its next tick.
 func SetHTTPClient(c *http.Client) {
 	(&httpClientAtomic).Store(c)
 }
 
+// loadHTTPClient returns exactly what was last given to SetHTTPClient, which
+// might be nil, so that a running poster can tell when it has been changed.
+func loadHTTPClient() *http.Client {
+	c, _ := (&httpClientAtomic).Load().(*http.Client)
+	return c
+}
+
 // GetHTTPClient returns the current *http.Client used in requests to post
 // metrics to Heroku's endpoint.  If nil, an reference to a new empty
 // http.Client will be returned instead.
 func GetHTTPClient() *http.Client {
-	c := (&httpClientAtomic).Load()
+	c := loadHTTPClient()
 	if c == nil {
 		return &http.Client{}
 	}
-	return c.(*http.Client)
+	return c
 }
 
 /*
@@ -229,8 +235,33 @@ type ErrorPoster func(error)
 // This should not happen in a sane environment and is probably worthy of
 // a Fatal exit even if bad metrics export might normally not be, because
 // your environment is messed up.
+//
+// Spawn is SpawnContext with a background context and no options.
 func Spawn(poster ErrorPoster) (logMessage string, cancel func(), err error) {
-	return realSpawn(poster)
+	return SpawnContext(context.Background(), poster)
+}
+
+// SpawnContext is Spawn, with the metrics-posting go-routine running under a
+// context derived from ctx, so that it stops when ctx is cancelled and can
+// see any values carried in ctx.  The returned cancel func cancels only the
+// derived context.  Options adjust the behavior of this one poster.
+func SpawnContext(ctx context.Context, poster ErrorPoster, opts ...Option) (logMessage string, cancel func(), err error) {
+	result, cancel, err := realSpawn(ctx, poster, opts)
+	return result.Message, cancel, err
+}
+
+// Start is Spawn, but with a machine-readable StartResult in place of the
+// logMessage, for callers who want to branch on what happened or to emit
+// structured logs.  The cancel and err return values are as for Spawn;
+// result.Started is true exactly when cancel is non-nil.
+func Start(poster ErrorPoster) (result StartResult, cancel func(), err error) {
+	return StartContext(context.Background(), poster)
+}
+
+// StartContext is Start with a parent context and options, as for
+// SpawnContext.
+func StartContext(ctx context.Context, poster ErrorPoster, opts ...Option) (result StartResult, cancel func(), err error) {
+	return realSpawn(ctx, poster, opts)
 }
 
 // HTTPFailureError indicates an unexpected HTTP response code
@@ -239,8 +270,23 @@ type HTTPFailureError struct {
 	ActualResponseCode   int
 	URL                  string
 	Comment              string
+	// Status is the status line text, such as "503 Service Unavailable".
+	Status string
+	// BodySnippet is the start of the response body, at most
+	// MaxBodySnippetBytes long.
+	BodySnippet string
+	// RetryAfter is the Retry-After response header, if any.
+	RetryAfter string
+	// RequestID is the X-Request-Id response header, if any.
+	RequestID string
+	// Latency is how long we waited for the response.
+	Latency time.Duration
 }
 
+// MaxBodySnippetBytes bounds how much of a failure response body we keep in
+// an HTTPFailureError.
+const MaxBodySnippetBytes = 512
+
 var _ error = HTTPFailureError{}
 
 func init() {
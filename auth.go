@@ -0,0 +1,169 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TokenProvider is the function signature for a callback which supplies a
+// bearer token, called afresh for each post so that it can handle refresh.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// WithHeader adds a static header to every metrics post, for destinations
+// which authenticate with an API key or similar.  The value is treated as a
+// secret: it is never included in a StartResult, and is scrubbed from any
+// error we pass to the ErrorPoster.
+func WithHeader(name, value string) Option {
+	return func(r *runner) {
+		if name == "" {
+			r.optionFailed(errors.New("hmetrics: WithHeader given an empty header name"))
+			return
+		}
+		if r.staticHeaders == nil {
+			r.staticHeaders = make(http.Header)
+		}
+		r.staticHeaders.Add(name, value)
+		r.addSecret(value)
+	}
+}
+
+// WithBearerToken calls provider before each metrics post and sends the
+// result as `Authorization: Bearer <token>`.  If provider returns an error
+// then we skip that post and report the error.  Tokens are scrubbed from any
+// error we pass to the ErrorPoster.
+func WithBearerToken(provider TokenProvider) Option {
+	return func(r *runner) {
+		if provider == nil {
+			r.optionFailed(errors.New("hmetrics: WithBearerToken given a nil provider"))
+			return
+		}
+		r.tokenProvider = provider
+	}
+}
+
+// WithHMACSignature signs the body of each metrics post, exactly as sent, with
+// HMAC-SHA256 under key and puts the signature in the named header, in the
+// form `sha256=<hex>`.
+func WithHMACSignature(header string, key []byte) Option {
+	return func(r *runner) {
+		if header == "" || len(key) == 0 {
+			r.optionFailed(errors.New("hmetrics: WithHMACSignature needs a header name and a key"))
+			return
+		}
+		r.hmacHeader = header
+		r.hmacKey = append([]byte(nil), key...)
+	}
+}
+
+// minScrubLength avoids scrubbing short values, which would mangle errors
+// without protecting anything worth protecting.
+const minScrubLength = 4
+
+func (r *runner) addSecret(s string) {
+	if len(s) >= minScrubLength {
+		r.secrets = append(r.secrets, s)
+	}
+}
+
+// headerNames lists the names of the extra headers which we send, for
+// reporting; their values are never reported.
+func (r *runner) headerNames() []string {
+	var names []string
+	for name := range r.staticHeaders {
+		names = append(names, name)
+	}
+	if r.tokenProvider != nil {
+		names = append(names, "Authorization")
+	}
+	if r.hmacHeader != "" {
+		names = append(names, http.CanonicalHeaderKey(r.hmacHeader))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addAuthHeaders sets our authentication headers for a post of body.
+func (r *runner) addAuthHeaders(ctx context.Context, header http.Header, body []byte) error {
+	for name, values := range r.staticHeaders {
+		header[name] = values
+	}
+	if r.tokenProvider != nil {
+		token, err := r.tokenProvider(ctx)
+		if err != nil {
+			return fmt.Errorf("hmetrics: token provider failed: %w", err)
+		}
+		if token != r.lastToken {
+			// The previous token might still be echoed back by a post which
+			// was in flight when it was rotated; older ones are forgotten.
+			r.previousToken = r.lastToken
+			r.lastToken = token
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if r.hmacHeader != "" {
+		mac := hmac.New(sha256.New, r.hmacKey)
+		_, _ = mac.Write(body)
+		header.Set(r.hmacHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return nil
+}
+
+// scrub removes any secrets we know of from s.
+func (r *runner) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, "redacted")
+	}
+	for _, token := range []string{r.lastToken, r.previousToken} {
+		if len(token) >= minScrubLength {
+			s = strings.ReplaceAll(s, token, "redacted")
+		}
+	}
+	return s
+}
+
+// scrubError removes secrets from an error.  An HTTPFailureError keeps its
+// type, with the parts which might echo back what we sent scrubbed, and a
+// *url.Error is rebuilt likewise; any other error which mentions a secret is
+// wrapped, so that its message is scrubbed
+// while errors.Is and errors.As still work.
+func (r *runner) scrubError(err error) error {
+	if err == nil || (len(r.secrets) == 0 && r.lastToken == "" && r.previousToken == "") {
+		return err
+	}
+	var failure HTTPFailureError
+	if errors.As(err, &failure) {
+		failure.BodySnippet = r.scrub(failure.BodySnippet)
+		failure.Comment = r.scrub(failure.Comment)
+		return failure
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		// Rebuilt rather than wrapped, so that those who look inside, such
+		// as SlogAttrs, see only scrubbed text.
+		return &url.Error{Op: urlErr.Op, URL: r.scrub(urlErr.URL), Err: r.scrubError(urlErr.Err)}
+	}
+	if msg := err.Error(); r.scrub(msg) != msg {
+		return scrubbedError{msg: r.scrub(msg), err: err}
+	}
+	return err
+}
+
+// scrubbedError is an error with secrets removed from its message.
+type scrubbedError struct {
+	msg string
+	err error
+}
+
+func (e scrubbedError) Error() string { return e.msg }
+func (e scrubbedError) Unwrap() error { return e.err }
@@ -0,0 +1,106 @@
+package hmetrics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthHeaders(t *testing.T) {
+	key := []byte("signing-key")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get("X-Signature") != want {
+			t.Errorf("bad signature header %q, expected %q", req.Header.Get("X-Signature"), want)
+		}
+		if req.Header.Get("X-Api-Key") != "static-key-value" {
+			t.Errorf("static header missing")
+		}
+		// echo back the credentials, as some broken servers do
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, "bad credentials: "+req.Header.Get("Authorization")+" "+req.Header.Get("X-Api-Key"))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &runner{metricsURL: u}
+	for _, opt := range []Option{
+		WithHeader("X-Api-Key", "static-key-value"),
+		WithBearerToken(func(context.Context) (string, error) { return "fresh-token", nil }),
+		WithHMACSignature("X-Signature", key),
+	} {
+		opt(r)
+	}
+	if r.optionErr != nil {
+		t.Fatalf("option error: %s", r.optionErr)
+	}
+	if have := strings.Join(r.headerNames(), ","); have != "Authorization,X-Api-Key,X-Signature" {
+		t.Errorf("headerNames()=%q", have)
+	}
+
+	err = r.submit(context.Background(), ts.Client(), []byte(`{"counters":{},"gauges":{}}`))
+	var failure HTTPFailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected HTTPFailureError, got %v", err)
+	}
+	if msg := err.Error(); strings.Contains(msg, "fresh-token") || strings.Contains(msg, "static-key-value") {
+		t.Errorf("secret leaked into error: %s", msg)
+	}
+	if !strings.Contains(failure.BodySnippet, "Bearer redacted") {
+		t.Errorf("expected scrubbed echo in body snippet, have %q", failure.BodySnippet)
+	}
+
+	r = &runner{metricsURL: u}
+	WithBearerToken(func(context.Context) (string, error) { return "", errors.New("vault sealed") })(r)
+	if err = r.submit(context.Background(), ts.Client(), []byte("{}")); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("token provider error not surfaced: %v", err)
+	}
+}
+
+func TestTokenRotationScrubbing(t *testing.T) {
+	tokens := []string{"token-one", "token-two", "token-three"}
+	n := 0
+	r := &runner{}
+	WithBearerToken(func(context.Context) (string, error) {
+		token := tokens[n]
+		n++
+		return token, nil
+	})(r)
+	for range tokens {
+		if err := r.addAuthHeaders(context.Background(), make(http.Header), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.secrets) != 0 {
+		t.Errorf("rotated tokens accumulated as secrets: %q", r.secrets)
+	}
+	if have := r.scrub("token-one token-two token-three"); have != "token-one redacted redacted" {
+		t.Errorf("scrub gave %q, expected current and previous tokens redacted", have)
+	}
+
+	urlErr := &url.Error{Op: "Post", URL: "https://metrics.host/?k=token-three", Err: errors.New("rejected token-three")}
+	err := r.scrubError(urlErr)
+	var scrubbed *url.Error
+	if !errors.As(err, &scrubbed) || strings.Contains(err.Error(), "token-three") || strings.Contains(scrubbed.Err.Error(), "token-three") {
+		t.Errorf("url.Error not scrubbed: %v", err)
+	}
+	wrapped := fmt.Errorf("hmetrics: spool: %w: token-two", context.DeadlineExceeded)
+	err = r.scrubError(wrapped)
+	if strings.Contains(err.Error(), "token-two") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrapped error not scrubbed, or lost its chain: %v", err)
+	}
+}
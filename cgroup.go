@@ -0,0 +1,214 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted on Linux; in a
+// container, such as a Heroku dyno, this is the container's own cgroup.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is a threshold above which a cgroup v1 memory limit means
+// "no limit"; the kernel reports PAGE_COUNTER_MAX rounded to a page.
+const cgroupV1Unlimited = 1 << 60
+
+// errNoCgroupLimit is returned when there is no memory limit to find.
+var errNoCgroupLimit = errors.New("no cgroup memory limit")
+
+// cgroupFS reads the memory and CPU accounting files of either cgroup v2
+// (unified) or v1 hierarchies.
+type cgroupFS struct {
+	root string
+	v2   bool
+}
+
+func newCgroupFS(root string) cgroupFS {
+	_, err := os.Stat(filepath.Join(root, "cgroup.controllers"))
+	return cgroupFS{root: root, v2: err == nil}
+}
+
+func (fs cgroupFS) path(v1Controller, v1Name, v2Name string) string {
+	if fs.v2 {
+		return filepath.Join(fs.root, v2Name)
+	}
+	return filepath.Join(fs.root, v1Controller, v1Name)
+}
+
+func readCgroupInt(path string) (string, int64, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	s := strings.TrimSpace(string(raw))
+	if s == "max" {
+		return s, -1, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return s, 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return s, n, nil
+}
+
+// readCgroupStats parses files of "key value" lines, such as cpu.stat.
+func readCgroupStats(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stats := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			stats[fields[0]] = n
+		}
+	}
+	return stats, scanner.Err()
+}
+
+func (fs cgroupFS) memoryUsage() (int64, error) {
+	_, n, err := readCgroupInt(fs.path("memory", "memory.usage_in_bytes", "memory.current"))
+	return n, err
+}
+
+// memoryLimit returns the memory limit in bytes, or errNoCgroupLimit.
+func (fs cgroupFS) memoryLimit() (int64, error) {
+	_, n, err := readCgroupInt(fs.path("memory", "memory.limit_in_bytes", "memory.max"))
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n >= cgroupV1Unlimited {
+		return 0, errNoCgroupLimit
+	}
+	return n, nil
+}
+
+func (fs cgroupFS) oomKills() (int64, error) {
+	stats, err := readCgroupStats(fs.path("memory", "memory.oom_control", "memory.events"))
+	if err != nil {
+		return 0, err
+	}
+	return stats["oom_kill"], nil
+}
+
+// cpuThrottling returns the number of throttled periods and the total time
+// throttled in nanoseconds.
+func (fs cgroupFS) cpuThrottling() (periods, nanoseconds int64, err error) {
+	stats, err := readCgroupStats(fs.path("cpu", "cpu.stat", "cpu.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if fs.v2 {
+		return stats["nr_throttled"], stats["throttled_usec"] * 1000, nil
+	}
+	return stats["nr_throttled"], stats["throttled_time"], nil
+}
+
+// NewCgroupCollector returns a Collector of memory and CPU accounting from the
+// cgroup (v1 or v2) of the process, so is only useful on Linux.  Heroku
+// enforces dyno memory quotas through cgroups, so these show how close you
+// are to R14 and R15 errors:
+//
+//   - cgroup.memory.usage.bytes (gauge)
+//   - cgroup.memory.limit.bytes (gauge, absent if unlimited)
+//   - cgroup.memory.headroom.bytes (gauge): limit less usage
+//   - cgroup.memory.headroom.ratio (gauge): headroom as a fraction of limit
+//   - cgroup.memory.oom.kills (counter)
+//   - cgroup.cpu.throttled.periods (counter)
+//   - cgroup.cpu.throttled.seconds (counter)
+func NewCgroupCollector() Collector {
+	return newCgroupCollector(cgroupRoot)
+}
+
+type cgroupCollector struct {
+	fs     cgroupFS
+	seeded bool
+
+	oomKills         int64
+	throttledPeriods int64
+	throttledNS      int64
+}
+
+func newCgroupCollector(root string) *cgroupCollector {
+	c := &cgroupCollector{fs: newCgroupFS(root)}
+	c.seed()
+	return c
+}
+
+// seed records the counters' starting values, so that the first interval
+// reports only its own changes.
+func (c *cgroupCollector) seed() {
+	oom, err1 := c.fs.oomKills()
+	periods, ns, err2 := c.fs.cpuThrottling()
+	if err1 == nil && err2 == nil {
+		c.oomKills, c.throttledPeriods, c.throttledNS = oom, periods, ns
+		c.seeded = true
+	}
+}
+
+// Collect is the type-satisfying method which makes cgroupCollector a
+// Collector.  Each source is independent, so we report what we can.
+func (c *cgroupCollector) Collect(m *Metrics) error {
+	var problems []string
+
+	usage, usageErr := c.fs.memoryUsage()
+	if usageErr != nil {
+		problems = append(problems, usageErr.Error())
+	} else {
+		m.Gauges["cgroup.memory.usage.bytes"] = float64(usage)
+	}
+	limit, err := c.fs.memoryLimit()
+	switch {
+	case err == nil:
+		m.Gauges["cgroup.memory.limit.bytes"] = float64(limit)
+		if usageErr == nil && limit > 0 {
+			m.Gauges["cgroup.memory.headroom.bytes"] = float64(limit - usage)
+			m.Gauges["cgroup.memory.headroom.ratio"] = float64(limit-usage) / float64(limit)
+		}
+	case errors.Is(err, errNoCgroupLimit):
+	default:
+		problems = append(problems, err.Error())
+	}
+
+	oom, oomErr := c.fs.oomKills()
+	if oomErr != nil {
+		problems = append(problems, oomErr.Error())
+	}
+	periods, ns, cpuErr := c.fs.cpuThrottling()
+	if cpuErr != nil {
+		problems = append(problems, cpuErr.Error())
+	}
+	if c.seeded {
+		if oomErr == nil {
+			m.Counters["cgroup.memory.oom.kills"] = float64(oom - c.oomKills)
+		}
+		if cpuErr == nil {
+			m.Counters["cgroup.cpu.throttled.periods"] = float64(periods - c.throttledPeriods)
+			m.Counters["cgroup.cpu.throttled.seconds"] = float64(ns-c.throttledNS) / 1e9
+		}
+	}
+	if oomErr == nil && cpuErr == nil {
+		c.oomKills, c.throttledPeriods, c.throttledNS = oom, periods, ns
+		c.seeded = true
+	}
+
+	if problems != nil {
+		return fmt.Errorf("reading cgroup: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
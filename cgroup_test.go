@@ -0,0 +1,97 @@
+package hmetrics
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyFixture copies a testdata tree to a temporary directory, so that tests
+// can modify it.
+func copyFixture(t *testing.T, src string) string {
+	t.Helper()
+	dst := t.TempDir()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o700)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o600)
+	})
+	if err != nil {
+		t.Fatalf("copying fixture %s: %s", src, err)
+	}
+	return dst
+}
+
+func TestCgroupCollectorFixtures(t *testing.T) {
+	for _, e := range []struct {
+		version string
+		v2      bool
+		usage   float64
+		limit   float64
+		cpuFile string
+		cpuNext string
+	}{
+		{"v1", false, 402653184, 536870912, "cpu/cpu.stat", "nr_periods 600\nnr_throttled 25\nthrottled_time 4500000000\n"},
+		{"v2", true, 268435456, 1073741824, "cpu.stat", "nr_periods 200\nnr_throttled 9\nthrottled_usec 2000000\n"},
+	} {
+		t.Run(e.version, func(t *testing.T) {
+			root := copyFixture(t, filepath.Join("testdata", "cgroup", e.version))
+			c := newCgroupCollector(root)
+			if c.fs.v2 != e.v2 {
+				t.Fatalf("detected v2=%v, expected %v", c.fs.v2, e.v2)
+			}
+			m := newTestMetrics()
+			if err := c.Collect(&m); err != nil {
+				t.Fatalf("Collect: %s", err)
+			}
+			if m.Gauges["cgroup.memory.usage.bytes"] != e.usage || m.Gauges["cgroup.memory.limit.bytes"] != e.limit {
+				t.Errorf("usage/limit %v/%v, expected %v/%v", m.Gauges["cgroup.memory.usage.bytes"], m.Gauges["cgroup.memory.limit.bytes"], e.usage, e.limit)
+			}
+			if have, want := m.Gauges["cgroup.memory.headroom.ratio"], (e.limit-e.usage)/e.limit; have != want {
+				t.Errorf("headroom ratio %v, expected %v", have, want)
+			}
+			if m.Counters["cgroup.cpu.throttled.periods"] != 0 || m.Counters["cgroup.memory.oom.kills"] != 0 {
+				t.Errorf("first interval should report no change: %v", m.Counters)
+			}
+
+			if err := os.WriteFile(filepath.Join(root, e.cpuFile), []byte(e.cpuNext), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			m = newTestMetrics()
+			if err := c.Collect(&m); err != nil {
+				t.Fatalf("second Collect: %s", err)
+			}
+			if m.Counters["cgroup.cpu.throttled.periods"] != 5 || m.Counters["cgroup.cpu.throttled.seconds"] != 1.5 {
+				t.Errorf("throttling deltas %v periods %v seconds, expected 5 and 1.5",
+					m.Counters["cgroup.cpu.throttled.periods"], m.Counters["cgroup.cpu.throttled.seconds"])
+			}
+		})
+	}
+}
+
+func TestCgroupUnlimited(t *testing.T) {
+	root := copyFixture(t, filepath.Join("testdata", "cgroup", "v2"))
+	if err := os.WriteFile(filepath.Join(root, "memory.max"), []byte("max\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMetrics()
+	if err := newCgroupCollector(root).Collect(&m); err != nil {
+		t.Fatalf("Collect: %s", err)
+	}
+	if _, ok := m.Gauges["cgroup.memory.limit.bytes"]; ok {
+		t.Errorf("unlimited cgroup reported a limit")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Collector contributes metrics of its own to each interval's payload,
+// alongside the Go runtime metrics.  Collect is called once per interval,
+// from the poster's go-routine, and should add the change over the interval
+// of anything cumulative to m.Counters and current values to m.Gauges.
+// Names should follow the dotted form of the runtime metrics, such as
+// "process.memory.rss.bytes".
+//
+// If Collect returns an error, it is passed to the ErrorPoster and whatever
+// the collector did add is still posted.
+type Collector interface {
+	Collect(m *Metrics) error
+}
+
+// CollectorFunc adapts a function to be a Collector.
+type CollectorFunc func(m *Metrics) error
+
+// Collect calls f(m).
+func (f CollectorFunc) Collect(m *Metrics) error {
+	return f(m)
+}
+
+// WithCollector adds collectors, whose metrics will be included in each post.
+func WithCollector(collectors ...Collector) Option {
+	return func(r *runner) {
+		for _, c := range collectors {
+			if c == nil {
+				r.optionFailed(errors.New("hmetrics: WithCollector given a nil collector"))
+				return
+			}
+		}
+		r.collectors = append(r.collectors, collectors...)
+	}
+}
+
+// runCollectors adds the metrics from all our collectors to m.  Values which
+// are NaN or infinite can't be encoded as JSON, so would stop the whole
+// payload from being posted; they are removed, and their names reported.
+func (r *runner) runCollectors(m *Metrics) {
+	for _, c := range r.collectors {
+		if err := c.Collect(m); err != nil {
+			r.poster(fmt.Errorf("hmetrics: collector %T: %w", c, err))
+		}
+	}
+	var dropped []string
+	for _, values := range []map[string]float64{m.Counters, m.Gauges} {
+		for name, value := range values {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				delete(values, name)
+				dropped = append(dropped, name)
+			}
+		}
+	}
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		r.poster(fmt.Errorf("hmetrics: dropped non-finite metrics: %s", strings.Join(dropped, ", ")))
+	}
+}
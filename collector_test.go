@@ -0,0 +1,58 @@
+package hmetrics
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// newTestMetrics gives an empty Metrics, ready for a Collector to fill in.
+func newTestMetrics() Metrics {
+	return Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+}
+
+func TestRunCollectors(t *testing.T) {
+	var posted []error
+	r := &runner{poster: func(e error) { posted = append(posted, e) }}
+	WithCollector(
+		CollectorFunc(func(m *Metrics) error {
+			m.Gauges["app.partial"] = 1
+			return errors.New("half-read")
+		}),
+		CollectorFunc(func(m *Metrics) error {
+			m.Counters["app.jobs"] = 2
+			return nil
+		}),
+	)(r)
+
+	m := newTestMetrics()
+	r.runCollectors(&m)
+	if m.Gauges["app.partial"] != 1 || m.Counters["app.jobs"] != 2 {
+		t.Errorf("collected metrics missing: %+v", m)
+	}
+	if len(posted) != 1 || !strings.Contains(posted[0].Error(), "half-read") {
+		t.Errorf("expected the one collector error posted, got %v", posted)
+	}
+
+	posted = nil
+	r.collectors = []Collector{CollectorFunc(func(m *Metrics) error {
+		m.Gauges["app.ratio"] = math.NaN()
+		m.Counters["app.total"] = math.Inf(1)
+		return nil
+	})}
+	m = newTestMetrics()
+	m.Gauges["go.memory.heap.bytes"] = 1
+	r.runCollectors(&m)
+	if len(m.Gauges) != 1 || len(m.Counters) != 0 {
+		t.Errorf("non-finite metrics kept, or others lost: %+v", m)
+	}
+	if len(posted) != 1 || !strings.Contains(posted[0].Error(), "app.ratio, app.total") {
+		t.Errorf("expected the dropped names posted, got %v", posted)
+	}
+
+	WithCollector(nil)(r)
+	if r.optionErr == nil {
+		t.Errorf("nil collector accepted")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewDashboard returns an http.Handler rendering a self-contained HTML page,
+// with no external assets, showing sparklines of each metric in h and the
+// status and recent errors of the poster feeding it.  It does no
+// authentication of its own: mount it behind your admin authentication.
+func NewDashboard(h *History) http.Handler {
+	return dashboard{history: h}
+}
+
+type dashboard struct {
+	history *History
+}
+
+// dashboardFirst lists the runtime metrics which lead the page, in order;
+// everything else follows alphabetically.
+var dashboardFirst = []string{
+	"go.memory.heap.bytes",
+	"go.memory.heap.objects",
+	"go.memory.stack.bytes",
+	"go.gc.goal",
+	"go.routines",
+	"go.gc.collections",
+	"go.gc.pause.ns",
+}
+
+const (
+	sparklineWidth  = 240
+	sparklineHeight = 40
+)
+
+type dashboardChart struct {
+	Name     string
+	Latest   string
+	Min, Max string
+	Points   string // SVG polyline points
+}
+
+type dashboardPage struct {
+	Generated time.Time
+	Status    Status
+	Charts    []dashboardChart
+}
+
+func (d dashboard) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := dashboardPage{
+		Generated: time.Now(),
+		Status:    d.history.Status(),
+	}
+	for _, name := range dashboardOrder(d.history.Names()) {
+		if chart, ok := newDashboardChart(name, d.history.Recent(name, 0)); ok {
+			page.Charts = append(page.Charts, chart)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, page); err != nil {
+		http.Error(w, "hmetrics: rendering dashboard failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func dashboardOrder(names []string) []string {
+	present := make(map[string]bool, len(names))
+	for _, n := range names {
+		present[n] = true
+	}
+	ordered := make([]string, 0, len(names))
+	for _, n := range dashboardFirst {
+		if present[n] {
+			ordered = append(ordered, n)
+			delete(present, n)
+		}
+	}
+	rest := make([]string, 0, len(present))
+	for n := range present {
+		rest = append(rest, n)
+	}
+	sort.Strings(rest)
+	return append(ordered, rest...)
+}
+
+func newDashboardChart(name string, points []Point) (dashboardChart, bool) {
+	if len(points) == 0 {
+		return dashboardChart{}, false
+	}
+	lo, hi := points[0].Value, points[0].Value
+	for _, p := range points {
+		if p.Value < lo {
+			lo = p.Value
+		}
+		if p.Value > hi {
+			hi = p.Value
+		}
+	}
+	span := hi - lo
+	if span == 0 {
+		span = 1
+	}
+	var coords strings.Builder
+	for i, p := range points {
+		x := 0.0
+		if len(points) > 1 {
+			x = float64(i) * sparklineWidth / float64(len(points)-1)
+		}
+		y := sparklineHeight - (p.Value-lo)*sparklineHeight/span
+		fmt.Fprintf(&coords, "%.1f,%.1f ", x, y)
+	}
+	return dashboardChart{
+		Name:   name,
+		Latest: formatDashboardValue(points[len(points)-1].Value),
+		Min:    formatDashboardValue(lo),
+		Max:    formatDashboardValue(hi),
+		Points: strings.TrimSpace(coords.String()),
+	}, true
+}
+
+func formatDashboardValue(v float64) string {
+	return fmt.Sprintf("%.6g", v)
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ts": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+	"tsp": func(t *time.Time) string {
+		if t == nil {
+			return "never"
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="20">
+<title>hmetrics</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.2em 1em 0.2em 0; vertical-align: middle; }
+.charts td.value { font-family: monospace; text-align: right; }
+svg { background: #f6f6f6; }
+polyline { fill: none; stroke: #6762a6; stroke-width: 1.5; }
+.errors td { font-family: monospace; font-size: 90%; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<h1>hmetrics</h1>
+<table class="status">
+<tr><th>Mode</th><td>{{.Status.ModeName}}</td></tr>
+<tr><th>Endpoint</th><td>{{with .Status.Endpoint}}{{.}}{{else}}<span class="muted">none</span>{{end}}</td></tr>
+<tr><th>Started</th><td>{{ts .Status.Started}}</td></tr>
+<tr><th>Last success</th><td>{{tsp .Status.LastSuccess}}</td></tr>
+<tr><th>Last error</th><td>{{tsp .Status.LastError}}</td></tr>
+</table>
+
+<h2>Metrics</h2>
+{{if .Charts}}
+<table class="charts">
+<tr><th>Metric</th><th>Recent</th><th>Latest</th><th>Min</th><th>Max</th></tr>
+{{range .Charts}}
+<tr>
+<td>{{.Name}}</td>
+<td><svg width="` + fmt.Sprint(sparklineWidth) + `" height="` + fmt.Sprint(sparklineHeight) + `" viewBox="-1 -1 ` + fmt.Sprint(sparklineWidth+2) + ` ` + fmt.Sprint(sparklineHeight+2) + `"><polyline points="{{.Points}}"/></svg></td>
+<td class="value">{{.Latest}}</td>
+<td class="value">{{.Min}}</td>
+<td class="value">{{.Max}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">No samples collected yet.</p>
+{{end}}
+
+<h2>Recent errors</h2>
+{{if .Status.Errors}}
+<table class="errors">
+{{range .Status.Errors}}<tr><td>{{ts .Time}}</td><td>{{.Message}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">None.</p>
+{{end}}
+<p class="muted">Generated {{ts .Generated}}</p>
+</body>
+</html>
+`))
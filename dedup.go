@@ -0,0 +1,185 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// WithErrorSummaries collapses repeats of the same class of error: the first
+// in each window is passed to the ErrorPoster as-is, and the rest are counted
+// and reported as a single RepeatedError at the first tick or error after the
+// window has passed.  When a post succeeds after failures, a RecoveredNotice
+// is sent.  This keeps log volume sane during a long endpoint outage.  A
+// window of 0 disables this.
+func WithErrorSummaries(window time.Duration) Option {
+	return func(r *runner) {
+		r.summaryWindow = window
+	}
+}
+
+// RepeatedError summarizes errors which were suppressed by WithErrorSummaries.
+type RepeatedError struct {
+	// Count is how many errors were suppressed.
+	Count int
+	// Period is how long the summary covers.
+	Period time.Duration
+	// Last is the most recent of the suppressed errors.
+	Last error
+}
+
+// Error is the type-satisfying method which lets a RepeatedError be an "error".
+func (e RepeatedError) Error() string {
+	return fmt.Sprintf("hmetrics: %d identical failures in the last %s: %s", e.Count, e.Period, e.Last)
+}
+
+// Unwrap gives access to the most recent of the suppressed errors.
+func (e RepeatedError) Unwrap() error {
+	return e.Last
+}
+
+// RecoveredNotice is passed to the ErrorPoster, when using
+// WithErrorSummaries, once a post succeeds after there have been failures.
+type RecoveredNotice struct {
+	// Failures is how many errors there were since the last success.
+	Failures int
+	// Since is when the first of those errors happened.
+	Since time.Time
+	// Recovered is when the successful post happened.
+	Recovered time.Time
+}
+
+// Error is the type-satisfying method which lets a RecoveredNotice be passed
+// to an ErrorPoster.
+func (n RecoveredNotice) Error() string {
+	return fmt.Sprintf("hmetrics: posting recovered after %d failures over %s",
+		n.Failures, n.Recovered.Sub(n.Since).Truncate(time.Second))
+}
+
+// errorDeduper sits between the runner and the caller's ErrorPoster.  It is
+// only called from the one go-routine, so needs no locking.
+type errorDeduper struct {
+	window time.Duration
+	poster ErrorPoster
+	now    func() time.Time
+
+	classes      map[string]*errorClassState
+	failures     int
+	failingSince time.Time
+}
+
+type errorClassState struct {
+	windowStart time.Time
+	suppressed  int
+	last        error
+}
+
+func newErrorDeduper(window time.Duration, poster ErrorPoster) *errorDeduper {
+	return &errorDeduper{
+		window:  window,
+		poster:  poster,
+		now:     time.Now,
+		classes: make(map[string]*errorClassState),
+	}
+}
+
+// errorClass decides which errors count as "the same".  HTTP failures go by
+// status code, since their text carries request IDs and latencies.  Anything
+// else goes by the type of the innermost error and the text of the whole,
+// with numbers (durations, addresses, ports) blanked out, so that a refused
+// connection and a DNS failure are told apart, as are the different places
+// in which we wrap errors.
+func errorClass(e error) string {
+	var httpFailure HTTPFailureError
+	if errors.As(e, &httpFailure) {
+		return fmt.Sprintf("http/%d", httpFailure.ActualResponseCode)
+	}
+	root := e
+	for {
+		next := errors.Unwrap(root)
+		if next == nil {
+			break
+		}
+		root = next
+	}
+	return fmt.Sprintf("%T/%s", root, errorClassNumbers.ReplaceAllString(e.Error(), "#"))
+}
+
+var errorClassNumbers = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+
+// post is an ErrorPoster.
+func (d *errorDeduper) post(e error) {
+	var (
+		dryRun DryRunPayload
+		memory MemoryWarning
+	)
+	if errors.As(e, &dryRun) || errors.As(e, &memory) || errors.Is(e, context.Canceled) {
+		d.poster(e)
+		return
+	}
+
+	now := d.now()
+	if d.failures == 0 {
+		d.failingSince = now
+	}
+	d.failures++
+
+	class := errorClass(e)
+	state, ok := d.classes[class]
+	if !ok {
+		d.classes[class] = &errorClassState{windowStart: now}
+		d.poster(e)
+		return
+	}
+	state.suppressed++
+	state.last = e
+	d.summarize(state, now)
+}
+
+// summarize reports what has been suppressed for one class, if its window
+// has passed, and starts a new window.
+func (d *errorDeduper) summarize(state *errorClassState, now time.Time) {
+	elapsed := now.Sub(state.windowStart)
+	if elapsed < d.window || state.suppressed == 0 {
+		return
+	}
+	d.poster(RepeatedError{Count: state.suppressed, Period: elapsed.Truncate(time.Second), Last: state.last})
+	state.windowStart = now
+	state.suppressed = 0
+	state.last = nil
+}
+
+// flush is called on each tick, so that summaries are sent once their window
+// has passed even if no more errors of that class arrive to trigger them.
+func (d *errorDeduper) flush() {
+	now := d.now()
+	for _, state := range d.classes {
+		d.summarize(state, now)
+	}
+}
+
+// succeeded is called after each successful post.
+func (d *errorDeduper) succeeded() {
+	if d.failures == 0 {
+		return
+	}
+	now := d.now()
+	for class, state := range d.classes {
+		if state.suppressed > 0 {
+			d.poster(RepeatedError{
+				Count:  state.suppressed,
+				Period: now.Sub(state.windowStart).Truncate(time.Second),
+				Last:   state.last,
+			})
+		}
+		delete(d.classes, class)
+	}
+	d.poster(RecoveredNotice{Failures: d.failures, Since: d.failingSince, Recovered: now})
+	d.failures = 0
+}
@@ -0,0 +1,97 @@
+package hmetrics
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestErrorDeduper(t *testing.T) {
+	var posted []error
+	d := newErrorDeduper(10*time.Minute, func(e error) { posted = append(posted, e) })
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.now = func() time.Time { return clock }
+
+	failure := HTTPFailureError{ExpectedResponseCode: 200, ActualResponseCode: 503, URL: "https://metrics.host/"}
+	for i := 0; i < 31; i++ {
+		d.post(failure)
+		clock = clock.Add(20 * time.Second)
+	}
+	d.post(errors.New("something else"))
+
+	if len(posted) != 3 {
+		t.Fatalf("expected first failure, one summary and the different error; got %d: %v", len(posted), posted)
+	}
+	var repeated RepeatedError
+	if !errors.As(posted[1], &repeated) {
+		t.Fatalf("second post was not a summary: %v", posted[1])
+	}
+	if repeated.Count != 30 || repeated.Period != 10*time.Minute {
+		t.Errorf("summary is %d over %s, expected 30 over 10m", repeated.Count, repeated.Period)
+	}
+
+	posted = nil
+	d.post(failure)
+	d.succeeded()
+	if len(posted) != 2 {
+		t.Fatalf("expected summary and recovery; got %d: %v", len(posted), posted)
+	}
+	var recovered RecoveredNotice
+	if !errors.As(posted[1], &recovered) || recovered.Failures != 33 {
+		t.Errorf("bad recovery notice: %v", posted[1])
+	}
+
+	posted = nil
+	d.succeeded()
+	if len(posted) != 0 {
+		t.Errorf("success without failures should be silent, got: %v", posted)
+	}
+}
+
+func TestErrorDeduperFlush(t *testing.T) {
+	var posted []error
+	d := newErrorDeduper(time.Minute, func(e error) { posted = append(posted, e) })
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.now = func() time.Time { return clock }
+
+	refused := &url.Error{Op: "Post", URL: "https://metrics.host/", Err: errors.New("dial tcp 10.0.0.1:443: connect: connection refused")}
+	for i := 0; i < 3; i++ {
+		d.post(refused)
+	}
+	d.flush()
+	if len(posted) != 1 {
+		t.Fatalf("expected only the first error within the window; got %d: %v", len(posted), posted)
+	}
+	clock = clock.Add(time.Minute)
+	d.flush()
+	var repeated RepeatedError
+	if len(posted) != 2 || !errors.As(posted[1], &repeated) || repeated.Count != 2 {
+		t.Fatalf("expected a summary of 2 on the tick after the window; got %v", posted)
+	}
+	d.flush()
+	if len(posted) != 2 {
+		t.Errorf("nothing suppressed, but flush posted: %v", posted[2:])
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	refused := func(addr string) error {
+		return &url.Error{Op: "Post", URL: "https://metrics.host/", Err: errors.New("dial tcp " + addr + ": connect: connection refused")}
+	}
+	for _, c := range []struct {
+		a, b error
+		same bool
+	}{
+		{refused("10.0.0.1:443"), refused("10.0.0.2:443"), true},
+		{refused("10.0.0.1:443"), &url.Error{Op: "Post", URL: "https://metrics.host/", Err: errors.New("no such host")}, false},
+		{fmt.Errorf("hmetrics: spool: %w", errors.New("disk full")), fmt.Errorf("hmetrics: collector: %w", errors.New("bad file")), false},
+		{HTTPFailureError{ActualResponseCode: 503, RequestID: "a1"}, HTTPFailureError{ActualResponseCode: 503, RequestID: "b2"}, true},
+		{HTTPFailureError{ActualResponseCode: 503}, HTTPFailureError{ActualResponseCode: 502}, false},
+	} {
+		if same := errorClass(c.a) == errorClass(c.b); same != c.same {
+			t.Errorf("errorClass(%q) vs errorClass(%q): same=%v, expected %v", c.a, c.b, same, c.same)
+		}
+	}
+}
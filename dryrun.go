@@ -0,0 +1,127 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Mode describes what, if anything, a call to Start or Spawn started; see
+// StartResult.Mode.
+type Mode int
+
+// These are the modes which Spawn can report.
+const (
+	// ModeNotStarted means that nothing is running, because of
+	// configuration or an error.
+	ModeNotStarted Mode = iota
+	// ModePosting is normal operation: metrics are posted to the endpoint.
+	ModePosting
+	// ModeDryRun means that metrics are collected and encoded, but the
+	// payloads are handed to the DryRunReporter instead of being posted.
+	ModeDryRun
+	// ModeDisabled means that nothing is running because EnvKeyDisable
+	// told us not to.
+	ModeDisabled
+)
+
+// String gives a short human label for the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeNotStarted:
+		return "not-started"
+	case ModePosting:
+		return "posting"
+	case ModeDryRun:
+		return "dry-run"
+	case ModeDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// DryRunPayload is what would have been posted, had we not been in dry-run
+// mode.  It satisfies the error interface so that, absent a DryRunReporter,
+// it can be handed to your ErrorPoster for logging.
+type DryRunPayload struct {
+	// URL is the redacted form of the endpoint, or empty if there is none.
+	URL       string
+	Payload   []byte
+	Collected time.Time
+}
+
+// Error is the type-satisfying method which lets a DryRunPayload be passed to
+// an ErrorPoster.
+func (p DryRunPayload) Error() string {
+	if p.URL == "" {
+		return fmt.Sprintf("hmetrics dry-run: would post: %s", p.Payload)
+	}
+	return fmt.Sprintf("hmetrics dry-run: would post to %q: %s", p.URL, p.Payload)
+}
+
+var _ error = DryRunPayload{}
+
+// DryRunReporter is the function signature for a callback which receives each
+// payload in dry-run mode.
+type DryRunReporter func(DryRunPayload)
+
+var (
+	dryRunAtomic         int32
+	dryRunReporterAtomic atomic.Value
+)
+
+// SetDryRun enables or disables dry-run mode, in which we go through the
+// entire collection and encoding pipeline but do not post the results.
+// In dry-run mode, we do not need an endpoint to be configured.
+// SetDryRun returns the previous value.
+// SetDryRun is safe to call at any time from any go-routine, but is only
+// referenced in Spawn, so affects future calls to Spawn.
+// The EnvKeyDryRun environment variable overrides this.
+func SetDryRun(enabled bool) (previous bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	return atomic.SwapInt32(&dryRunAtomic, v) != 0
+}
+
+// currentDryRun is the read-only accessor for SetDryRun.
+func currentDryRun() bool {
+	return atomic.LoadInt32(&dryRunAtomic) != 0
+}
+
+// SetDryRunReporter provides a callback for dry-run payloads.  If none is
+// set (or it is set to nil) then each payload is passed to the ErrorPoster
+// given to Spawn.
+// SetDryRunReporter is safe to call at any time from any go-routine.
+func SetDryRunReporter(r DryRunReporter) {
+	(&dryRunReporterAtomic).Store(r)
+}
+
+// WithDryRun puts this one poster into dry-run mode, regardless of
+// SetDryRun.  If reporter is non-nil then it receives the payloads in
+// preference to any set with SetDryRunReporter.
+func WithDryRun(reporter DryRunReporter) Option {
+	return func(r *runner) {
+		r.mode = ModeDryRun
+		r.dryRunReporter = reporter
+	}
+}
+
+// reportDryRun hands a payload to whichever callback should receive it.
+func (r *runner) reportDryRun(p DryRunPayload) {
+	if r.dryRunReporter != nil {
+		r.dryRunReporter(p)
+		return
+	}
+	if reporter, _ := (&dryRunReporterAtomic).Load().(DryRunReporter); reporter != nil {
+		reporter(p)
+		return
+	}
+	r.poster(p)
+}
@@ -0,0 +1,179 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// These environment variables are consulted by Spawn, before starting, and
+// override any values set in code with the corresponding Set function.  This
+// lets operators tune behavior with `heroku config:set` without a new build.
+// Durations are in the format accepted by time.ParseDuration and must be
+// positive.  A variable which is set but empty is treated as unset.
+const (
+	// EnvKeyURL overrides EnvKeyEndpoint as the place to post metrics to,
+	// for use with sinks other than Heroku's own.
+	EnvKeyURL = "HMETRICS_URL"
+
+	// EnvKeyMaxFailureBackoff corresponds to SetMaxFailureBackoff.
+	EnvKeyMaxFailureBackoff = "HMETRICS_MAX_FAILURE_BACKOFF"
+	// EnvKeyResetFailureBackoffAfter corresponds to SetResetFailureBackoffAfter.
+	EnvKeyResetFailureBackoffAfter = "HMETRICS_RESET_FAILURE_BACKOFF_AFTER"
+	// EnvKeyResetFailureBackoffTo corresponds to SetResetFailureBackoffTo.
+	EnvKeyResetFailureBackoffTo = "HMETRICS_RESET_FAILURE_BACKOFF_TO"
+	// EnvKeyPostInterval corresponds to SetMetricsPostInterval.
+	EnvKeyPostInterval = "HMETRICS_POST_INTERVAL"
+	// EnvKeyHTTPTimeout corresponds to SetHTTPTimeout.
+	EnvKeyHTTPTimeout = "HMETRICS_HTTP_TIMEOUT"
+	// EnvKeyUserAgent corresponds to SetHTTPUserAgent.
+	EnvKeyUserAgent = "HMETRICS_USER_AGENT"
+	// EnvKeyDryRun overrides SetDryRun for each Spawn while it is set,
+	// without changing it; the value is parsed with strconv.ParseBool.
+	EnvKeyDryRun = "HMETRICS_DRY_RUN"
+
+	// EnvKeyAutoMemoryLimit, if set, enables WithAutoMemoryLimit with the
+	// given fraction, overriding any fraction given in code.  Once it is
+	// unset, a later Spawn goes back to the fraction given in code, if any.
+	EnvKeyAutoMemoryLimit = "HMETRICS_AUTO_MEMORY_LIMIT"
+
+	// EnvKeyDisable, if set to a true value (per strconv.ParseBool), keeps
+	// Spawn from starting anything, even if an endpoint is configured.
+	EnvKeyDisable = "HMETRICS_DISABLE"
+)
+
+// EnvError indicates that an HMETRICS_* environment variable was set to a
+// value which we could not use.  Spawn will not start if any are invalid.
+type EnvError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+// Error is the type-satisfying method which lets an EnvError be an "error".
+func (e EnvError) Error() string {
+	return fmt.Sprintf("hmetrics: invalid environment variable %s=%q: %s", e.Key, e.Value, e.Err)
+}
+
+// Unwrap gives access to the underlying parse failure.
+func (e EnvError) Unwrap() error {
+	return e.Err
+}
+
+var errNotPositive = errors.New("duration must be positive")
+
+// lookupSetting returns the value of one of our tunables, which counts as
+// unset if it is empty, so that `heroku config:set KEY=` clears it.
+func lookupSetting(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
+}
+
+var envDurations = []struct {
+	key    string
+	setter func(time.Duration) time.Duration
+}{
+	{EnvKeyMaxFailureBackoff, SetMaxFailureBackoff},
+	{EnvKeyResetFailureBackoffAfter, SetResetFailureBackoffAfter},
+	{EnvKeyResetFailureBackoffTo, SetResetFailureBackoffTo},
+	{EnvKeyPostInterval, SetMetricsPostInterval},
+	{EnvKeyHTTPTimeout, SetHTTPTimeout},
+}
+
+// envOverrides holds what the environment says about one Spawn, rather than
+// about the package-level tunables.
+type envOverrides struct {
+	// dryRun is nil unless EnvKeyDryRun is set.
+	dryRun *bool
+}
+
+// applyEnvironment reads the HMETRICS_* tunables and applies them, returning
+// those which only affect the Spawn at hand.  Every variable is validated
+// before any is applied, so that an error leaves the configuration untouched.
+func applyEnvironment() (envOverrides, error) {
+	var (
+		apply     []func()
+		overrides envOverrides
+	)
+
+	for _, d := range envDurations {
+		value, ok := lookupSetting(d.key)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return envOverrides{}, EnvError{Key: d.key, Value: value, Err: err}
+		}
+		if parsed <= 0 {
+			return envOverrides{}, EnvError{Key: d.key, Value: value, Err: errNotPositive}
+		}
+		setter := d.setter
+		apply = append(apply, func() { _ = setter(parsed) })
+	}
+
+	if ua, ok := lookupSetting(EnvKeyUserAgent); ok {
+		apply = append(apply, func() { SetHTTPUserAgent(ua) })
+	}
+
+	// This is held apart from the fraction given in code, rather than
+	// overwriting it as the others do, so must be cleared when unset, lest it
+	// outlive the variable.
+	fraction := 0.0
+	if value, ok := lookupSetting(EnvKeyAutoMemoryLimit); ok {
+		var err error
+		fraction, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return envOverrides{}, EnvError{Key: EnvKeyAutoMemoryLimit, Value: value, Err: err}
+		}
+		if !validAutoMemoryFraction(fraction) {
+			return envOverrides{}, EnvError{Key: EnvKeyAutoMemoryLimit, Value: value, Err: errors.New("fraction must be greater than 0 and at most 1")}
+		}
+	}
+	apply = append(apply, func() { atomic.StoreUint64(&envAutoMemoryFractionBits, math.Float64bits(fraction)) })
+
+	if value, ok := lookupSetting(EnvKeyDryRun); ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return envOverrides{}, EnvError{Key: EnvKeyDryRun, Value: value, Err: err}
+		}
+		overrides.dryRun = &enabled
+	}
+
+	for _, f := range apply {
+		f()
+	}
+	return overrides, nil
+}
+
+// disabledByEnvironment reports whether EnvKeyDisable tells us to do nothing.
+func disabledByEnvironment() (bool, error) {
+	value, ok := lookupSetting(EnvKeyDisable)
+	if !ok {
+		return false, nil
+	}
+	disabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, EnvError{Key: EnvKeyDisable, Value: value, Err: err}
+	}
+	return disabled, nil
+}
+
+// lookupEndpoint returns the environment variable which names our metrics
+// endpoint, and its value.  HMETRICS_URL, if present, wins.
+func lookupEndpoint() (key, value string, ok bool) {
+	for _, key = range []string{EnvKeyURL, EnvKeyEndpoint} {
+		if value, ok = os.LookupEnv(key); ok {
+			return key, value, true
+		}
+	}
+	return EnvKeyEndpoint, "", false
+}
@@ -0,0 +1,112 @@
+package hmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplyEnvironment(t *testing.T) {
+	previous := SetHTTPTimeout(0)
+	defer SetHTTPTimeout(previous)
+	previousInterval := SetMetricsPostInterval(0)
+	defer SetMetricsPostInterval(previousInterval)
+
+	t.Setenv(EnvKeyHTTPTimeout, "3s")
+	if _, err := applyEnvironment(); err != nil {
+		t.Fatalf("applyEnvironment failed: %s", err)
+	}
+	if have := currentHTTPTimeout(); have != 3*time.Second {
+		t.Errorf("HTTP timeout not applied from environ: have %s", have)
+	}
+
+	for i, e := range []struct{ key, value string }{
+		{EnvKeyPostInterval, "often"},
+		{EnvKeyPostInterval, "-5s"},
+		{EnvKeyDryRun, "maybe"},
+	} {
+		t.Run(e.key, func(t *testing.T) {
+			t.Setenv(EnvKeyHTTPTimeout, "7s")
+			t.Setenv(e.key, e.value)
+			_, err := applyEnvironment()
+			var envErr EnvError
+			if !errors.As(err, &envErr) {
+				t.Fatalf("[%d] %s=%q: expected EnvError, got %v", i, e.key, e.value, err)
+			}
+			if envErr.Key != e.key {
+				t.Errorf("[%d] error blamed %q, expected %q", i, envErr.Key, e.key)
+			}
+			if have := currentHTTPTimeout(); have != 3*time.Second {
+				t.Errorf("[%d] partial application despite error: timeout now %s", i, have)
+			}
+		})
+	}
+}
+
+func TestLookupEndpoint(t *testing.T) {
+	t.Setenv(EnvKeyEndpoint, "https://heroku.invalid/")
+	if key, value, ok := lookupEndpoint(); !ok || key != EnvKeyEndpoint || value != "https://heroku.invalid/" {
+		t.Errorf("lookupEndpoint()=%q,%q,%v; expected Heroku endpoint", key, value, ok)
+	}
+	t.Setenv(EnvKeyURL, "https://sink.invalid/")
+	if key, value, ok := lookupEndpoint(); !ok || key != EnvKeyURL || value != "https://sink.invalid/" {
+		t.Errorf("lookupEndpoint()=%q,%q,%v; expected override endpoint", key, value, ok)
+	}
+}
+
+func TestSpawnDisabled(t *testing.T) {
+	t.Setenv(EnvKeyEndpoint, "https://heroku.invalid/")
+	t.Setenv(EnvKeyDisable, "true")
+	result, cancel, err := Start(func(e error) { t.Error(e) })
+	if err != nil {
+		t.Fatalf("Start failed: %s", err)
+	}
+	if cancel != nil {
+		cancel()
+		t.Fatalf("started despite %s: %s", EnvKeyDisable, result.Message)
+	}
+	if result.Mode != ModeDisabled || result.Reason != ReasonDisabled {
+		t.Errorf("mode is %v, reason %v; expected %v, %v", result.Mode, result.Reason, ModeDisabled, ReasonDisabled)
+	}
+
+	// The kill-switch wins over broken configuration, and nothing is applied.
+	previous := SetHTTPTimeout(0)
+	defer SetHTTPTimeout(previous)
+	t.Setenv(EnvKeyHTTPTimeout, "13s")
+	t.Setenv(EnvKeyPostInterval, "often")
+	result, cancel, err = Start(func(e error) { t.Error(e) })
+	if err != nil || cancel != nil || result.Mode != ModeDisabled {
+		t.Errorf("with bad config, got mode %v, err %v: %s", result.Mode, err, result.Message)
+	}
+	if have := currentHTTPTimeout(); have != previous {
+		t.Errorf("environment applied despite %s: timeout now %s", EnvKeyDisable, have)
+	}
+
+	t.Setenv(EnvKeyDisable, "maybe")
+	if _, _, err = Start(func(e error) { t.Error(e) }); err == nil {
+		t.Errorf("expected error from unparseable %s", EnvKeyDisable)
+	}
+}
+
+func TestDryRunEnvironment(t *testing.T) {
+	defer SetDryRun(SetDryRun(false))
+	t.Setenv(EnvKeyEndpoint, "")
+	t.Setenv(EnvKeyDryRun, "true")
+	result, cancel, err := Start(func(error) {})
+	if cancel != nil {
+		cancel()
+	}
+	if err != nil || result.Mode != ModeDryRun || !result.Settings.DryRun {
+		t.Fatalf("with %s, got mode %v, err %v: %s", EnvKeyDryRun, result.Mode, err, result.Message)
+	}
+	if currentDryRun() {
+		t.Errorf("%s changed the package-level dry-run setting", EnvKeyDryRun)
+	}
+
+	t.Setenv(EnvKeyDryRun, "")
+	result, cancel, _ = Start(func(error) {})
+	if cancel != nil {
+		cancel()
+		t.Errorf("still in mode %v once %s was cleared", result.Mode, EnvKeyDryRun)
+	}
+}
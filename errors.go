@@ -6,13 +6,31 @@ package hmetrics
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 func (e HTTPFailureError) Error() string {
-	return fmt.Sprintf("http: got %q instead of %d from: %q (%s)",
-		e.ActualResponseCode,
-		e.ExpectedResponseCode,
-		e.URL,
-		e.Comment,
-	)
+	var b strings.Builder
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d", e.ActualResponseCode)
+	}
+	fmt.Fprintf(&b, "http: got %s instead of %d from: %q", status, e.ExpectedResponseCode, e.URL)
+	if e.Latency != 0 {
+		fmt.Fprintf(&b, " after %s", e.Latency.Round(time.Millisecond))
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " request-id=%q", e.RequestID)
+	}
+	if e.RetryAfter != "" {
+		fmt.Fprintf(&b, " retry-after=%q", e.RetryAfter)
+	}
+	if e.Comment != "" {
+		fmt.Fprintf(&b, " (%s)", e.Comment)
+	}
+	if e.BodySnippet != "" {
+		fmt.Fprintf(&b, ": %q", e.BodySnippet)
+	}
+	return b.String()
 }
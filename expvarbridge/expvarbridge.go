@@ -0,0 +1,153 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+// Package expvarbridge reports variables published through expvar as
+// hmetrics metrics.
+//
+// It is a separate package because importing expvar registers a handler
+// for /debug/vars on http.DefaultServeMux, which not every user of hmetrics
+// wants.
+package expvarbridge
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"go.pennock.tech/hmetrics"
+)
+
+// DefaultPrefix is the metric name prefix used by New when the Config has an
+// empty Prefix.
+const DefaultPrefix = "expvar"
+
+// DefaultDeny is used when Config.Deny is nil.  The runtime's memstats are
+// already covered by the metrics which hmetrics always posts, and cmdline is
+// not numeric.
+var DefaultDeny = []string{"cmdline", "memstats", "memstats.*"}
+
+// Config controls which expvar variables New reports, and how.
+//
+// Patterns are matched with path.Match against the flattened name, without
+// the prefix, such as "http.requests" for the "requests" key of a Map
+// published as "http".  "*" matches across dots.  A name is reported if it
+// matches some Allow pattern (or Allow is empty) and no Deny pattern.  A
+// variable whose own name matches a Deny pattern is skipped without being
+// evaluated, so costly variables are best denied by name, as DefaultDeny does
+// for memstats.
+type Config struct {
+	Prefix string
+	Allow  []string
+	Deny   []string
+}
+
+// New returns an hmetrics.Collector of everything published through
+// expvar, walked each interval.  Int and Float variables are reported
+// directly; any other variable, such as a Map or Func, is decoded from its
+// JSON form and each numeric value within it reported, with nested object
+// keys joined by dots.  Strings, booleans and arrays are skipped.
+//
+// Everything is reported as a gauge, since expvar does not say which values
+// are cumulative.
+//
+// An error is returned if any of the patterns is malformed.
+func New(cfg Config) (hmetrics.Collector, error) {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.Deny == nil {
+		cfg.Deny = DefaultDeny
+	}
+	for _, pattern := range append(append([]string(nil), cfg.Allow...), cfg.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("expvarbridge: pattern %q: %w", pattern, err)
+		}
+	}
+	return &collector{cfg: cfg}, nil
+}
+
+type collector struct {
+	cfg Config
+}
+
+var expvarKeyReplacer = strings.NewReplacer(" ", "_", "/", "_")
+
+// Collect is the type-satisfying method which makes collector an
+// hmetrics.Collector.  Variables which do not decode are skipped, and the first
+// such problem reported.
+func (c *collector) Collect(m *hmetrics.Metrics) error {
+	var firstErr error
+	expvar.Do(func(kv expvar.KeyValue) {
+		name := expvarKeyReplacer.Replace(kv.Key)
+		if c.denied(name) {
+			// Checked before String, which for some variables, such as
+			// memstats, is costly.
+			return
+		}
+		switch v := kv.Value.(type) {
+		case *expvar.Int:
+			c.add(m, name, float64(v.Value()))
+		case *expvar.Float:
+			c.add(m, name, v.Value())
+		default:
+			var decoded any
+			if err := json.Unmarshal([]byte(v.String()), &decoded); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("expvar %q: %w", kv.Key, err)
+				}
+				return
+			}
+			c.flatten(m, name, decoded)
+		}
+	})
+	return firstErr
+}
+
+// flatten adds every number within value to m.
+func (c *collector) flatten(m *hmetrics.Metrics, name string, value any) {
+	switch v := value.(type) {
+	case float64:
+		c.add(m, name, v)
+	case map[string]any:
+		for key, inner := range v {
+			c.flatten(m, name+"."+expvarKeyReplacer.Replace(key), inner)
+		}
+	}
+}
+
+// add reports one value, if the patterns allow it.  An expvar.Float can
+// hold NaN or an infinity, which can't be posted, so those are skipped.
+func (c *collector) add(m *hmetrics.Metrics, name string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || !c.allowed(name) {
+		return
+	}
+	m.Gauges[c.cfg.Prefix+"."+name] = value
+}
+
+func (c *collector) denied(name string) bool {
+	for _, pattern := range c.cfg.Deny {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *collector) allowed(name string) bool {
+	if c.denied(name) {
+		return false
+	}
+	if len(c.cfg.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range c.cfg.Allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package expvarbridge
+
+import (
+	"expvar"
+	"math"
+	"strings"
+	"testing"
+
+	"go.pennock.tech/hmetrics"
+)
+
+// evaluated is set when hmetrics_test_func is called.
+var evaluated bool
+
+func init() {
+	expvar.NewInt("hmetrics_test_int").Set(42)
+	expvar.NewFloat("hmetrics_test_float").Set(2.5)
+	// Skipped, being unencodable.
+	expvar.NewFloat("hmetrics_test_nan").Set(math.NaN())
+	mv := expvar.NewMap("hmetrics_test_map")
+	mv.Add("hits", 7)
+	mv.Add("misses", 3)
+	expvar.Publish("hmetrics_test_func", expvar.Func(func() any {
+		evaluated = true
+		return map[string]any{
+			"name":  "ignored",
+			"inner": map[string]any{"depth": 9},
+			"list":  []int{1, 2},
+		}
+	}))
+}
+
+func TestExpvarCollector(t *testing.T) {
+	c, err := New(Config{Allow: []string{"hmetrics_test_*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := hmetrics.Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+	if err := c.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"expvar.hmetrics_test_int":              42,
+		"expvar.hmetrics_test_float":            2.5,
+		"expvar.hmetrics_test_map.hits":         7,
+		"expvar.hmetrics_test_map.misses":       3,
+		"expvar.hmetrics_test_func.inner.depth": 9,
+	}
+	for name, value := range want {
+		if have, ok := m.Gauges[name]; !ok || have != value {
+			t.Errorf("gauge %s=%v (present %v), expected %v", name, have, ok, value)
+		}
+	}
+	if len(m.Gauges) != len(want) {
+		t.Errorf("got %d gauges, expected %d: %v", len(m.Gauges), len(want), m.Gauges)
+	}
+
+	c, _ = New(Config{
+		Prefix: "app",
+		Allow:  []string{"hmetrics_test_map.*"},
+		Deny:   []string{"*.misses"},
+	})
+	m = hmetrics.Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+	_ = c.Collect(&m)
+	if len(m.Gauges) != 1 || m.Gauges["app.hmetrics_test_map.hits"] != 7 {
+		t.Errorf("allow/deny not applied: %v", m.Gauges)
+	}
+
+	m = hmetrics.Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+	c, _ = New(Config{})
+	_ = c.Collect(&m)
+	for name := range m.Gauges {
+		if strings.HasPrefix(name, "expvar.memstats") {
+			t.Errorf("memstats not denied by default: %s", name)
+		}
+	}
+
+	m = hmetrics.Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+	c, _ = New(Config{Deny: []string{"hmetrics_test_func"}})
+	evaluated = false
+	_ = c.Collect(&m)
+	if evaluated {
+		t.Errorf("denied variable was evaluated")
+	}
+
+	if _, err := New(Config{Deny: []string{"["}}); err == nil {
+		t.Errorf("malformed pattern accepted")
+	}
+}
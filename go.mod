@@ -1,3 +1,3 @@
 module go.pennock.tech/hmetrics
 
-go 1.19
+go 1.21
@@ -0,0 +1,30 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// WithGzip compresses the body of each metrics post, sending it with
+// `Content-Encoding: gzip`.  This is off by default, because Heroku's
+// endpoint is not documented to accept it; use it with other sinks which
+// you know to support it.
+func WithGzip() Option {
+	return func(r *runner) {
+		r.gzip = true
+	}
+}
+
+// gzipPayload compresses payload into buf, which is reset first.
+func gzipPayload(buf *bytes.Buffer, payload []byte) error {
+	buf.Reset()
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(payload); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,59 @@
+package hmetrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+	received := make(chan map[string]map[string]float64, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("missing Content-Encoding: gzip, have %q", req.Header.Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			t.Errorf("gzip.NewReader: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload map[string]map[string]float64
+		if err = json.NewDecoder(zr).Decode(&payload); err != nil {
+			t.Errorf("decoding decompressed payload: %s", err)
+		}
+		received <- payload
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &runner{metricsURL: u, baseline: newCounterBaseline()}
+	WithGzip()(r)
+
+	var buf bytes.Buffer
+	metrics, _, _ := gatherMetrics(0, 0)
+	if err = encodeMetrics(&buf, metrics); err != nil {
+		t.Fatalf("encodeMetrics: %s", err)
+	}
+	if err = r.submit(context.Background(), ts.Client(), buf.Bytes()); err != nil {
+		t.Fatalf("submit: %s", err)
+	}
+	payload := <-received
+	if _, ok := payload["gauges"]["go.routines"]; !ok {
+		t.Errorf("round-tripped payload missing go.routines gauge: %v", payload)
+	}
+
+	// a second post must not be corrupted by reuse of the compression buffer
+	if err = r.submit(context.Background(), ts.Client(), buf.Bytes()); err != nil {
+		t.Fatalf("second submit: %s", err)
+	}
+	<-received
+}
@@ -0,0 +1,243 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// History retains the metrics from the most recent intervals in memory, for
+// on-dyno debugging without waiting for Heroku's dashboard.  Create one with
+// NewHistory, pass it to WithHistory, and query it with Recent or mount it as
+// an http.Handler.  A History is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	samples []Sample // ring buffer
+	next    int
+	count   int
+
+	status Status
+}
+
+// Status describes the poster feeding a History.
+type Status struct {
+	Started  time.Time `json:"started"`
+	Mode     Mode      `json:"-"`
+	ModeName string    `json:"mode"`
+	// Endpoint is the redacted form of the URL we post to.
+	Endpoint string `json:"endpoint,omitempty"`
+	// LastSuccess and LastError are nil until there has been one.
+	LastSuccess *time.Time    `json:"last_success,omitempty"`
+	LastError   *time.Time    `json:"last_error,omitempty"`
+	Errors      []ErrorRecord `json:"errors,omitempty"`
+}
+
+// ErrorRecord is one error passed to the ErrorPoster.
+type ErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// historyErrorsKept is how many recent errors a History keeps for its Status.
+const historyErrorsKept = 10
+
+// Sample is the metrics of one interval, with the time of collection.
+type Sample struct {
+	Time time.Time `json:"time"`
+	Metrics
+}
+
+// Point is one value of one metric.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// NewHistory returns a History retaining the last size intervals.
+func NewHistory(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{samples: make([]Sample, size)}
+}
+
+// WithHistory records each interval's metrics into h, as well as posting
+// them.  The same History may be shared by several posters, though that is
+// rarely useful.
+func WithHistory(h *History) Option {
+	return func(r *runner) {
+		r.history = h
+	}
+}
+
+// started records the start of a poster.
+func (h *History) started(t time.Time, mode Mode, endpoint string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.Started = t
+	h.status.Mode = mode
+	h.status.ModeName = mode.String()
+	h.status.Endpoint = endpoint
+}
+
+// succeeded records a successful post.
+func (h *History) succeeded(t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.LastSuccess = &t
+}
+
+// recordError keeps a recent error for the Status; notices which are not
+// really errors are ignored.
+func (h *History) recordError(t time.Time, e error) {
+	var (
+		dryRun    DryRunPayload
+		recovered RecoveredNotice
+		memory    MemoryWarning
+	)
+	if errors.As(e, &dryRun) || errors.As(e, &recovered) || errors.As(e, &memory) || errors.Is(e, context.Canceled) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.LastError = &t
+	h.status.Errors = append(h.status.Errors, ErrorRecord{Time: t, Message: e.Error()})
+	if extra := len(h.status.Errors) - historyErrorsKept; extra > 0 {
+		h.status.Errors = append(h.status.Errors[:0], h.status.Errors[extra:]...)
+	}
+}
+
+// Status returns the state of the poster feeding this History, including its
+// most recent errors, newest last.
+func (h *History) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.status
+	status.Errors = append([]ErrorRecord(nil), h.status.Errors...)
+	return status
+}
+
+// add records a sample; the maps are copied so that the caller may reuse them.
+func (h *History) add(t time.Time, m Metrics) {
+	s := Sample{Time: t, Metrics: Metrics{
+		Counters: make(map[string]float64, len(m.Counters)),
+		Gauges:   make(map[string]float64, len(m.Gauges)),
+	}}
+	for k, v := range m.Counters {
+		s.Counters[k] = v
+	}
+	for k, v := range m.Gauges {
+		s.Gauges[k] = v
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = s
+	h.next = (h.next + 1) % len(h.samples)
+	if h.count < len(h.samples) {
+		h.count++
+	}
+}
+
+// Samples returns up to the last n samples, oldest first; n <= 0 means all
+// those retained.
+func (h *History) Samples(n int) []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n <= 0 || n > h.count {
+		n = h.count
+	}
+	out := make([]Sample, n)
+	size := len(h.samples)
+	for i := 0; i < n; i++ {
+		out[i] = h.samples[(h.next-n+i+size)%size]
+	}
+	return out
+}
+
+// Recent returns up to the last n values of the named metric, which may be a
+// counter or a gauge, oldest first.  Intervals in which the metric was absent
+// are skipped.  n <= 0 means all those retained.
+func (h *History) Recent(name string, n int) []Point {
+	samples := h.Samples(0)
+	var points []Point
+	for _, s := range samples {
+		if v, ok := s.Counters[name]; ok {
+			points = append(points, Point{Time: s.Time, Value: v})
+		} else if v, ok := s.Gauges[name]; ok {
+			points = append(points, Point{Time: s.Time, Value: v})
+		}
+	}
+	if n > 0 && len(points) > n {
+		points = points[len(points)-n:]
+	}
+	return points
+}
+
+// Names returns the sorted names of all metrics in the retained history.
+func (h *History) Names() []string {
+	seen := make(map[string]bool)
+	for _, s := range h.Samples(0) {
+		for k := range s.Counters {
+			seen[k] = true
+		}
+		for k := range s.Gauges {
+			seen[k] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP returns the history as JSON.  With a `name` query parameter, the
+// response is the Recent points for that metric; otherwise it is the retained
+// Samples.  An `n` query parameter limits how many are returned.
+func (h *History) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	n := 0
+	if ns := query.Get("n"); ns != "" {
+		var err error
+		if n, err = strconv.Atoi(ns); err != nil {
+			http.Error(w, "bad n parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var body any
+	if name := query.Get("name"); name != "" {
+		body = struct {
+			Name   string  `json:"name"`
+			Points []Point `json:"points"`
+		}{name, h.Recent(name, n)}
+	} else {
+		body = struct {
+			Status  Status   `json:"status"`
+			Samples []Sample `json:"samples"`
+		}{h.Status(), h.Samples(n)}
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(encoded, '\n'))
+}
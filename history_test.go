@@ -0,0 +1,115 @@
+package hmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	h := NewHistory(3)
+	start := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		h.add(start.Add(time.Duration(i)*time.Second), Metrics{
+			Counters: map[string]float64{"go.gc.collections": float64(i)},
+			Gauges:   map[string]float64{"go.routines": float64(10 + i)},
+		})
+	}
+
+	points := h.Recent("go.routines", 2)
+	if len(points) != 2 || points[0].Value != 13 || points[1].Value != 14 {
+		t.Errorf("Recent(go.routines, 2)=%v, expected 13 then 14", points)
+	}
+	if points := h.Recent("go.gc.collections", 0); len(points) != 3 || points[0].Value != 2 {
+		t.Errorf("Recent(go.gc.collections, 0)=%v, expected the 3 retained, from 2", points)
+	}
+	if points := h.Recent("no.such.metric", 5); len(points) != 0 {
+		t.Errorf("Recent for unknown metric gave %v", points)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?name=go.routines&n=1", nil))
+	var body struct {
+		Name   string  `json:"name"`
+		Points []Point `json:"points"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("bad JSON from handler: %s: %q", err, rec.Body.String())
+	}
+	if body.Name != "go.routines" || len(body.Points) != 1 || body.Points[0].Value != 14 {
+		t.Errorf("handler returned %+v", body)
+	}
+}
+
+func TestHistoryUnencodable(t *testing.T) {
+	h := NewHistory(1)
+	h.add(time.Now(), Metrics{Gauges: map[string]float64{"app.ratio": math.NaN()}})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("handler gave status %d for an unencodable history, expected 500", rec.Code)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	h := NewHistory(5)
+	h.started(time.Now(), ModePosting, "https://metrics.host/redacted-uuid-form")
+	for i := 0; i < 3; i++ {
+		h.add(time.Now(), Metrics{
+			Counters: map[string]float64{"app.jobs": float64(i)},
+			Gauges:   map[string]float64{"go.memory.heap.bytes": float64(1000 * i)},
+		})
+	}
+	h.recordError(time.Now(), errors.New("http: got <503> from somewhere"))
+
+	rec := httptest.NewRecorder()
+	NewDashboard(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	page := rec.Body.String()
+	if rec.Code != 200 || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("bad response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`<polyline points="0.0,40.0 120.0,20.0 240.0,0.0"/>`,
+		"go.memory.heap.bytes",
+		"app.jobs",
+		"posting",
+		"http: got &lt;503&gt; from somewhere",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+	if strings.Index(page, "go.memory.heap.bytes") > strings.Index(page, "app.jobs") {
+		t.Errorf("runtime metrics should precede custom metrics")
+	}
+	if strings.Contains(page, "http://") || strings.Contains(page, "src=") {
+		t.Errorf("dashboard should reference no external assets")
+	}
+}
+
+func TestHistoryStatusErrors(t *testing.T) {
+	h := NewHistory(1)
+	h.started(time.Now(), ModePosting, "")
+	status, _ := json.Marshal(h.Status())
+	if strings.Contains(string(status), "last_success") || strings.Contains(string(status), "last_error") {
+		t.Errorf("unset times should be omitted: %s", status)
+	}
+
+	h.recordError(time.Now(), fmt.Errorf("hmetrics: stopping: %w", context.Canceled))
+	h.recordError(time.Now(), fmt.Errorf("wrapped: %w", MemoryWarning{}))
+	h.recordError(time.Now(), fmt.Errorf("wrapped: %w", RecoveredNotice{}))
+	if s := h.Status(); s.LastError != nil || len(s.Errors) != 0 {
+		t.Errorf("notices recorded as post errors: %+v", s.Errors)
+	}
+	h.recordError(time.Now(), fmt.Errorf("wrapped: %w", HTTPFailureError{ActualResponseCode: 503}))
+	if s := h.Status(); s.LastError == nil || len(s.Errors) != 1 {
+		t.Errorf("wrapped failure not recorded: %+v", s)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPClientPrefix is the metric name prefix used by
+// NewHTTPClientMetrics when given an empty prefix.
+const DefaultHTTPClientPrefix = "http.client"
+
+// DefaultHTTPClientMaxHosts is the cardinality cap used by
+// NewHTTPClientMetrics when given a non-positive maxHosts.
+const DefaultHTTPClientMaxHosts = 20
+
+// HTTPClientOtherHost is the name under which requests are counted once the
+// cap on distinct hosts has been reached.
+const HTTPClientOtherHost = "other"
+
+// HTTPClientMetrics records metrics about outbound requests made through its
+// RoundTripper, per destination host, and is a Collector which reports them
+// each interval.  Dots and colons in host names are replaced with
+// underscores, so with the default prefix the metrics for api.example.com are:
+//
+//   - http.client.api_example_com.requests (counter)
+//   - http.client.api_example_com.errors (counter): no response at all
+//   - http.client.api_example_com.responses.1xx ... .5xx (counters)
+//   - http.client.api_example_com.latency.p50.ms, .p95.ms, .p99.ms, .max.ms
+//     (gauges, absent if there were no requests in the interval)
+//
+// To bound the number of metrics posted, only the first maxHosts distinct
+// hosts are tracked by name; the rest are combined as HTTPClientOtherHost.
+type HTTPClientMetrics struct {
+	prefix   string
+	maxHosts int
+
+	mu    sync.Mutex
+	hosts map[string]*hostStats
+}
+
+type hostStats struct {
+	requests int64
+	errors   int64
+	statuses [5]int64
+	latency  sampleWindow
+}
+
+// NewHTTPClientMetrics returns an HTTPClientMetrics reporting under prefix,
+// or DefaultHTTPClientPrefix if that's empty, tracking at most maxHosts hosts
+// by name, or DefaultHTTPClientMaxHosts if maxHosts is not positive.
+func NewHTTPClientMetrics(prefix string, maxHosts int) *HTTPClientMetrics {
+	if prefix == "" {
+		prefix = DefaultHTTPClientPrefix
+	}
+	if maxHosts <= 0 {
+		maxHosts = DefaultHTTPClientMaxHosts
+	}
+	return &HTTPClientMetrics{
+		prefix:   prefix,
+		maxHosts: maxHosts,
+		hosts:    make(map[string]*hostStats),
+	}
+}
+
+// RoundTripper wraps next, or http.DefaultTransport if next is nil, so that
+// requests through it are recorded.  For example:
+//
+//	client := &http.Client{Transport: hcm.RoundTripper(nil)}
+func (c *HTTPClientMetrics) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		elapsed := time.Since(start)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		stats := c.statsFor(req.URL.Host)
+		stats.requests++
+		if err != nil {
+			stats.errors++
+		} else if class := resp.StatusCode/100 - 1; class >= 0 && class < len(stats.statuses) {
+			stats.statuses[class]++
+		}
+		stats.latency.observe(float64(elapsed) / float64(time.Millisecond))
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var hostNameReplacer = strings.NewReplacer(".", "_", ":", "_", "[", "", "]", "")
+
+// statsFor returns the stats for host, applying the cardinality cap.  The
+// caller must hold c.mu.
+func (c *HTTPClientMetrics) statsFor(host string) *hostStats {
+	name := hostNameReplacer.Replace(strings.ToLower(host))
+	if name == "" {
+		name = "unknown"
+	}
+	if stats, ok := c.hosts[name]; ok {
+		return stats
+	}
+	named := len(c.hosts)
+	if _, ok := c.hosts[HTTPClientOtherHost]; ok {
+		named--
+	}
+	if named >= c.maxHosts {
+		name = HTTPClientOtherHost
+		if stats, ok := c.hosts[name]; ok {
+			return stats
+		}
+	}
+	stats := &hostStats{}
+	c.hosts[name] = stats
+	return stats
+}
+
+// Collect is the type-satisfying method which makes HTTPClientMetrics a
+// Collector.
+func (c *HTTPClientMetrics) Collect(m *Metrics) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for host, stats := range c.hosts {
+		prefix := c.prefix + "." + host
+		m.Counters[prefix+".requests"] = float64(stats.requests)
+		m.Counters[prefix+".errors"] = float64(stats.errors)
+		for i, n := range stats.statuses {
+			m.Counters[prefix+".responses."+string(rune('1'+i))+"xx"] = float64(n)
+		}
+		addPercentileGauges(m, &stats.latency, prefix+".latency", ".ms")
+		stats.requests, stats.errors, stats.statuses = 0, 0, [5]int64{}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package hmetrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPClientMetrics(t *testing.T) {
+	hcm := NewHTTPClientMetrics("", 2)
+	fake := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/fail":
+			return nil, errors.New("connection refused")
+		case "/missing":
+			return &http.Response{StatusCode: 404, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	})
+	client := &http.Client{Transport: hcm.RoundTripper(fake)}
+
+	for _, u := range []string{
+		"https://api.example.com/",
+		"https://api.example.com/missing",
+		"https://api.example.com/fail",
+		"http://localhost:8080/",
+		"https://third.example.net/",
+		"https://fourth.example.net/",
+	} {
+		resp, err := client.Get(u)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	m := newTestMetrics()
+	if err := hcm.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{
+		"http.client.api_example_com.requests":      3,
+		"http.client.api_example_com.errors":        1,
+		"http.client.api_example_com.responses.2xx": 1,
+		"http.client.api_example_com.responses.4xx": 1,
+		"http.client.localhost_8080.requests":       1,
+		"http.client.other.requests":                2,
+	} {
+		if have := m.Counters[name]; have != want {
+			t.Errorf("counter %s=%v, expected %v", name, have, want)
+		}
+	}
+	if _, ok := m.Counters["http.client.third_example_net.requests"]; ok {
+		t.Errorf("cardinality cap not applied")
+	}
+	if _, ok := m.Gauges["http.client.api_example_com.latency.p95.ms"]; !ok {
+		t.Errorf("missing latency gauge")
+	}
+
+	m = newTestMetrics()
+	_ = hcm.Collect(&m)
+	if m.Counters["http.client.api_example_com.requests"] != 0 {
+		t.Errorf("counters not reset between intervals")
+	}
+}
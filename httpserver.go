@@ -0,0 +1,148 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHTTPServerPrefix is the metric name prefix used by
+// NewHTTPServerMetrics when given an empty prefix.
+const DefaultHTTPServerPrefix = "http.server"
+
+// HTTPServerMetrics records metrics about the requests served through its
+// Middleware, and is a Collector which reports them each interval.  With the
+// default prefix, the metrics are:
+//
+//   - http.server.requests (counter)
+//   - http.server.requests.inflight (gauge)
+//   - http.server.responses.1xx ... http.server.responses.5xx (counters);
+//     a handler which panics counts as 5xx
+//   - http.server.latency.p50.ms, .p95.ms, .p99.ms, .max.ms (gauges, over
+//     the interval, absent if there were no requests)
+//
+// Typical use is:
+//
+//	hm := hmetrics.NewHTTPServerMetrics("")
+//	hmetrics.SpawnContext(ctx, poster, hmetrics.WithCollector(hm))
+//	http.ListenAndServe(addr, hm.Middleware(mux))
+type HTTPServerMetrics struct {
+	prefix   string
+	requests int64
+	inFlight int64
+	statuses [5]int64 // 1xx..5xx
+	latency  sampleWindow
+}
+
+// NewHTTPServerMetrics returns an HTTPServerMetrics reporting under prefix,
+// or DefaultHTTPServerPrefix if that's empty.  Use different prefixes for
+// different servers in the one process.
+func NewHTTPServerMetrics(prefix string) *HTTPServerMetrics {
+	if prefix == "" {
+		prefix = DefaultHTTPServerPrefix
+	}
+	return &HTTPServerMetrics{prefix: prefix}
+}
+
+// Middleware wraps next so that its requests are recorded.
+func (s *HTTPServerMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		atomic.AddInt64(&s.inFlight, 1)
+		sw := &statusRecorder{ResponseWriter: w}
+		returned := false
+		defer func() {
+			atomic.AddInt64(&s.inFlight, -1)
+			atomic.AddInt64(&s.requests, 1)
+			status := sw.status
+			switch {
+			case !returned:
+				// The handler panicked, which net/http turns into a 500 or
+				// an aborted response; either way, it's a server error.
+				// We let the panic carry on, undisturbed.
+				status = http.StatusInternalServerError
+			case status == 0:
+				// nothing written: net/http will send a 200
+				status = http.StatusOK
+			}
+			s.recordStatus(status)
+			s.latency.observe(float64(time.Since(start)) / float64(time.Millisecond))
+		}()
+		next.ServeHTTP(sw, req)
+		returned = true
+	})
+}
+
+func (s *HTTPServerMetrics) recordStatus(status int) {
+	if class := status/100 - 1; class >= 0 && class < len(s.statuses) {
+		atomic.AddInt64(&s.statuses[class], 1)
+	}
+}
+
+// Collect is the type-satisfying method which makes HTTPServerMetrics a
+// Collector.
+func (s *HTTPServerMetrics) Collect(m *Metrics) error {
+	m.Counters[s.prefix+".requests"] = float64(atomic.SwapInt64(&s.requests, 0))
+	m.Gauges[s.prefix+".requests.inflight"] = float64(atomic.LoadInt64(&s.inFlight))
+	for i := range s.statuses {
+		m.Counters[s.prefix+".responses."+string(rune('1'+i))+"xx"] = float64(atomic.SwapInt64(&s.statuses[i], 0))
+	}
+	addPercentileGauges(m, &s.latency, s.prefix+".latency", ".ms")
+	return nil
+}
+
+// statusRecorder remembers the status code written through it.  Unwrap lets
+// http.ResponseController reach the underlying writer's Flush, Hijack and so
+// on.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	// informational responses other than 101 are followed by the real one
+	if sr.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		sr.status = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Flush and Hijack are provided directly because many handlers, such as
+// websocket upgraders, still type-assert for http.Flusher and http.Hijacker
+// rather than using http.ResponseController.
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	if sr.status == 0 {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
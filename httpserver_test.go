@@ -0,0 +1,90 @@
+package hmetrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPServerMetrics(t *testing.T) {
+	hm := NewHTTPServerMetrics("")
+	handler := hm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/missing":
+			http.NotFound(w, req)
+		case "/broken":
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/flush":
+			w.(http.Flusher).Flush()
+		case "/panic":
+			panic("handler bug")
+		case "/upgrade":
+			if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+				t.Errorf("Hijack through middleware: %s", err)
+			}
+		}
+	}))
+	for _, path := range []string{"/", "/", "/flush", "/missing", "/broken", "/panic"} {
+		func() {
+			defer func() {
+				if p := recover(); p != nil && path != "/panic" {
+					t.Errorf("unexpected panic for %s: %v", path, p)
+				}
+			}()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}()
+	}
+	handler.ServeHTTP(hijackableRecorder{httptest.NewRecorder()}, httptest.NewRequest("GET", "/upgrade", nil))
+
+	m := newTestMetrics()
+	if err := hm.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{
+		"http.server.requests":      7,
+		"http.server.responses.2xx": 3,
+		"http.server.responses.4xx": 1,
+		"http.server.responses.5xx": 2,
+		"http.server.responses.1xx": 1,
+	} {
+		if m.Counters[name] != want {
+			t.Errorf("counter %s=%v, expected %v", name, m.Counters[name], want)
+		}
+	}
+	if _, ok := m.Gauges["http.server.latency.p99.ms"]; !ok {
+		t.Errorf("missing latency percentile gauge: %v", m.Gauges)
+	}
+	if m.Gauges["http.server.requests.inflight"] != 0 {
+		t.Errorf("in-flight gauge %v after all requests completed", m.Gauges["http.server.requests.inflight"])
+	}
+
+	m = newTestMetrics()
+	_ = hm.Collect(&m)
+	if m.Counters["http.server.requests"] != 0 {
+		t.Errorf("counters not reset between intervals")
+	}
+	if _, ok := m.Gauges["http.server.latency.p50.ms"]; ok {
+		t.Errorf("latency reported for an interval without requests")
+	}
+}
+
+// hijackableRecorder is a ResponseRecorder which can be hijacked, yielding
+// nothing useful.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestStatusRecorderHijackUnsupported(t *testing.T) {
+	sr := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := sr.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Hijack of a non-hijacker gave %v, expected ErrNotSupported", err)
+	}
+}
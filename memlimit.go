@@ -0,0 +1,78 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// envAutoMemoryFractionBits holds the math.Float64bits of the fraction from
+// EnvKeyAutoMemoryLimit, as applied by applyEnvironment, or 0 if unset.
+var envAutoMemoryFractionBits uint64
+
+// currentEnvAutoMemoryFraction returns the fraction from EnvKeyAutoMemoryLimit,
+// or 0 if it's not set.
+func currentEnvAutoMemoryFraction() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&envAutoMemoryFractionBits))
+}
+
+// validAutoMemoryFraction says whether fraction is usable as a fraction of
+// the quota.
+func validAutoMemoryFraction(fraction float64) bool {
+	return fraction > 0 && fraction <= 1
+}
+
+// WithAutoMemoryLimit sets the Go runtime's soft memory limit (as
+// debug.SetMemoryLimit) to fraction of the memory quota, found as for
+// WatchdogConfig, so that the garbage collector works harder before the
+// dyno exceeds its quota.  The chosen limit is reported in
+// StartResult.MemoryLimit and, on each tick, as the go.memory.limit.bytes
+// gauge.
+//
+// This is opt-in, and is also enabled by setting EnvKeyAutoMemoryLimit to the
+// fraction.  If GOMEMLIMIT is set in environ, we leave the limit alone, as
+// that is an explicit choice.  If no quota can be found, we leave the limit
+// alone and StartResult.MemoryLimit is zero; that is not an error.
+// The fraction must be greater than 0 and at most 1; 0.9 is a fair choice.
+func WithAutoMemoryLimit(fraction float64) Option {
+	return func(r *runner) {
+		if !validAutoMemoryFraction(fraction) {
+			r.optionFailed(fmt.Errorf("hmetrics: auto memory limit fraction %v not in (0,1]", fraction))
+			return
+		}
+		r.autoMemoryFraction = fraction
+	}
+}
+
+// applyAutoMemoryLimit sets the runtime memory limit, if we can and should,
+// returning the limit set or 0.
+func applyAutoMemoryLimit(fraction float64, cgroups cgroupFS) int64 {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		return 0
+	}
+	quota, err := resolveMemoryQuota(WatchdogConfig{}, cgroups)
+	if err != nil {
+		return 0
+	}
+	limit := int64(float64(quota) * fraction)
+	debug.SetMemoryLimit(limit)
+	return limit
+}
+
+// currentMemoryLimit returns the runtime's memory limit, without changing it.
+func currentMemoryLimit() int64 {
+	return debug.SetMemoryLimit(-1)
+}
+
+// memoryLimitGauge adds go.memory.limit.bytes to m, unless there is no limit.
+func memoryLimitGauge(m *Metrics) {
+	if limit := currentMemoryLimit(); limit != math.MaxInt64 {
+		m.Gauges["go.memory.limit.bytes"] = float64(limit)
+	}
+}
@@ -0,0 +1,61 @@
+package hmetrics
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime/debug"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAutoMemoryLimit(t *testing.T) {
+	previous := currentMemoryLimit()
+	defer debug.SetMemoryLimit(previous)
+
+	t.Setenv("GOMEMLIMIT", "")
+	cgroups := newCgroupFS(filepath.Join("testdata", "cgroup", "v2"))
+	if limit := applyAutoMemoryLimit(0.5, cgroups); limit != 512<<20 {
+		t.Fatalf("applyAutoMemoryLimit set %d, expected %d", limit, 512<<20)
+	}
+	m := newTestMetrics()
+	memoryLimitGauge(&m)
+	if m.Gauges["go.memory.limit.bytes"] != 512<<20 {
+		t.Errorf("go.memory.limit.bytes gauge is %v", m.Gauges["go.memory.limit.bytes"])
+	}
+
+	t.Setenv("GOMEMLIMIT", "100MiB")
+	if limit := applyAutoMemoryLimit(0.5, cgroups); limit != 0 {
+		t.Errorf("overrode explicit GOMEMLIMIT with %d", limit)
+	}
+}
+
+func TestAutoMemoryLimitEnvironment(t *testing.T) {
+	defer atomic.StoreUint64(&envAutoMemoryFractionBits, atomic.LoadUint64(&envAutoMemoryFractionBits))
+
+	t.Setenv(EnvKeyAutoMemoryLimit, "0.75")
+	if _, err := applyEnvironment(); err != nil {
+		t.Fatalf("applyEnvironment failed: %s", err)
+	}
+	if have := currentSettings().AutoMemoryLimit; have != 0.75 {
+		t.Errorf("Settings.AutoMemoryLimit=%v, expected 0.75", have)
+	}
+
+	for _, value := range []string{"lots", "0", "1.5"} {
+		t.Setenv(EnvKeyAutoMemoryLimit, value)
+		var envErr EnvError
+		if _, err := applyEnvironment(); !errors.As(err, &envErr) || envErr.Key != EnvKeyAutoMemoryLimit {
+			t.Errorf("%s=%q: expected EnvError, got %v", EnvKeyAutoMemoryLimit, value, err)
+		}
+	}
+	if have := currentEnvAutoMemoryFraction(); have != 0.75 {
+		t.Errorf("bad value was applied: fraction now %v", have)
+	}
+
+	t.Setenv(EnvKeyAutoMemoryLimit, "")
+	if _, err := applyEnvironment(); err != nil {
+		t.Fatalf("applyEnvironment with empty %s failed: %s", EnvKeyAutoMemoryLimit, err)
+	}
+	if have := currentEnvAutoMemoryFraction(); have != 0 {
+		t.Errorf("fraction %v outlived emptying %s", have, EnvKeyAutoMemoryLimit)
+	}
+}
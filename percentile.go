@@ -0,0 +1,92 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// maxWindowSamples bounds the memory used for percentiles in each interval;
+// beyond this, we keep a uniform random sample of what was observed.
+const maxWindowSamples = 4096
+
+// reportedPercentiles are the percentiles which we report for each window,
+// with the suffix used in the metric name.
+var reportedPercentiles = []struct {
+	suffix string
+	p      float64
+}{
+	{"p50", 50},
+	{"p95", 95},
+	{"p99", 99},
+}
+
+// sampleWindow accumulates observations over one interval, so that we can
+// report percentiles.  It is safe for concurrent use.
+type sampleWindow struct {
+	mu     sync.Mutex
+	values []float64
+	seen   int
+	// max is tracked apart from the sample, which might not include it.
+	max float64
+}
+
+// observe records a value, using reservoir sampling once full.
+func (w *sampleWindow) observe(v float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seen == 0 || v > w.max {
+		w.max = v
+	}
+	w.seen++
+	if len(w.values) < maxWindowSamples {
+		w.values = append(w.values, v)
+		return
+	}
+	if j := rand.Intn(w.seen); j < maxWindowSamples {
+		w.values[j] = v
+	}
+}
+
+// drain returns the interval's sampled observations, sorted, and the largest
+// observation, and starts a new interval.
+func (w *sampleWindow) drain() (values []float64, max float64) {
+	w.mu.Lock()
+	values, max = w.values, w.max
+	w.values = nil
+	w.seen = 0
+	w.mu.Unlock()
+	sort.Float64s(values)
+	return values, max
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// addPercentileGauges drains w and adds gauges named prefix.p50 and so on,
+// with unit (such as ".ms") appended.  If nothing was observed,
+// nothing is added: there's no meaningful value to report.
+func addPercentileGauges(m *Metrics, w *sampleWindow, prefix, unit string) {
+	values, max := w.drain()
+	if len(values) == 0 {
+		return
+	}
+	for _, rp := range reportedPercentiles {
+		m.Gauges[prefix+"."+rp.suffix+unit] = percentile(values, rp.p)
+	}
+	m.Gauges[prefix+".max"+unit] = max
+}
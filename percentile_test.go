@@ -0,0 +1,40 @@
+package hmetrics
+
+import (
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, e := range []struct{ p, want float64 }{{50, 5}, {95, 10}, {10, 1}, {0, 1}} {
+		if have := percentile(values, e.p); have != e.want {
+			t.Errorf("percentile(1..10, %v)=%v, expected %v", e.p, have, e.want)
+		}
+	}
+}
+
+func TestSampleWindowMax(t *testing.T) {
+	var w sampleWindow
+	for i := 0; i < 3*maxWindowSamples; i++ {
+		w.observe(1)
+	}
+	w.observe(1000)
+	for i := 0; i < 3*maxWindowSamples; i++ {
+		w.observe(1)
+	}
+	m := newTestMetrics()
+	addPercentileGauges(&m, &w, "test.latency", ".ms")
+	if have := m.Gauges["test.latency.max.ms"]; have != 1000 {
+		t.Errorf("max=%v, expected the true maximum of 1000 regardless of sampling", have)
+	}
+	if have := m.Gauges["test.latency.p50.ms"]; have != 1 {
+		t.Errorf("p50=%v, expected 1", have)
+	}
+
+	m = newTestMetrics()
+	w.observe(3)
+	addPercentileGauges(&m, &w, "test.latency", ".ms")
+	if have := m.Gauges["test.latency.max.ms"]; have != 3 {
+		t.Errorf("max=%v in a new interval, expected 3", have)
+	}
+}
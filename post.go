@@ -15,15 +15,116 @@ package hmetrics
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
 	"runtime"
+	"strings"
 	"time"
 )
 
-func postLoop(ctx context.Context, metricsURL *url.URL, poster ErrorPoster) error {
+// counterBaseline holds the cumulative runtime counters as of the most recent
+// collection, so that we can post per-interval deltas.  It lives outside of
+// postLoop so that when retryPostLoop restarts postLoop, the first post does
+// not report the lifetime totals of the process as one interval's worth.
+// It is only touched from the one go-routine, so needs no locking.
+type counterBaseline struct {
+	pauseTotalNS uint64
+	numGC        uint32
+}
+
+// newCounterBaseline seeds a baseline from the current runtime state, so that
+// the very first post covers only the first interval.
+func newCounterBaseline() *counterBaseline {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return &counterBaseline{
+		pauseTotalNS: stats.PauseTotalNs,
+		numGC:        stats.NumGC,
+	}
+}
+
+// runner holds the state of one spawned poster, shared by retryPostLoop and
+// each postLoop which it starts.
+type runner struct {
+	// metricsURL is nil only in dry-run mode without an endpoint.
+	metricsURL *url.URL
+	// redacted is the loggable form of metricsURL, or empty.
+	redacted string
+	// envKey is the environment variable which gave us metricsURL.
+	envKey   string
+	poster   ErrorPoster
+	mode     Mode
+	baseline *counterBaseline
+
+	dryRunReporter DryRunReporter
+	summaryWindow  time.Duration
+	dedup          *errorDeduper
+	gzip           bool
+	gzipBuf        bytes.Buffer
+
+	staticHeaders http.Header
+	tokenProvider TokenProvider
+	lastToken     string
+	previousToken string
+	hmacHeader    string
+	hmacKey       []byte
+	// secrets are scrubbed from errors before we pass them on.
+	secrets []string
+
+	spoolConfig *SpoolConfig
+	spool       *spool
+	history     *History
+	collectors  []Collector
+	watchdog    *watchdog
+
+	autoMemoryFraction float64
+	memoryLimit        int64
+
+	tlsConfig *tls.Config
+	// httpClient, if set, is used in preference to GetHTTPClient.
+	httpClient *http.Client
+	// postTimeout bounds each request, if positive.
+	postTimeout time.Duration
+
+	// optionErr records a problem found by an Option, to be returned by
+	// Spawn, since Options themselves cannot return errors.
+	optionErr error
+}
+
+// optionFailed records the first problem found by an Option.
+func (r *runner) optionFailed(err error) {
+	if r.optionErr == nil {
+		r.optionErr = err
+	}
+}
+
+// currentHTTPClient returns the client to use, and what it was derived from so
+// that changes can be noticed.
+func (r *runner) currentHTTPClient() (client, source *http.Client) {
+	if r.httpClient != nil {
+		return r.httpClient, r.httpClient
+	}
+	return GetHTTPClient(), loadHTTPClient()
+}
+
+// postSucceeded lets anything which cares know that a post went through.
+func (r *runner) postSucceeded() {
+	if r.dedup != nil {
+		r.dedup.succeeded()
+	}
+	if r.history != nil {
+		r.history.succeeded(time.Now())
+	}
+}
+
+// Option adjusts the behavior of a single poster started by SpawnContext or
+// StartContext, where the package-level Set functions affect all of them.
+type Option func(*runner)
+
+func postLoop(ctx context.Context, r *runner) error {
 	// we tick once every 20 seconds, so Heroku should get exactly 3 posts
 	// per minute, except that their logic allows 20 seconds for HTTP
 	// timeout, so they can then catch up with the next ticker immediately
@@ -31,6 +132,10 @@ func postLoop(ctx context.Context, metricsURL *url.URL, poster ErrorPoster) erro
 	// the metrics are evenly spaced.
 	//
 	// Also, if we fail to collect metrics, then we will skip that post.
+	//
+	// The interval and the HTTP client are re-checked on every tick, so that
+	// changes made via SetMetricsPostInterval and SetHTTPClient take effect
+	// without needing to tear down this loop (and lose our counter state).
 	ourTickerDuration := currentMetricsPostInterval()
 	maxSanePostDuration := ourTickerDuration - time.Second
 	intervalTicker := time.NewTicker(ourTickerDuration)
@@ -38,17 +143,9 @@ func postLoop(ctx context.Context, metricsURL *url.URL, poster ErrorPoster) erro
 	defer intervalTicker.Stop()
 
 	var buf bytes.Buffer
-	var pauseTotalNS uint64
-	var numGC uint32
 	var err error
 
-	httpClient := GetHTTPClient()
-	httpClient.Timeout = currentHTTPTimeout()
-
-	if httpClient.Timeout > maxSanePostDuration {
-		httpClient.Timeout = maxSanePostDuration
-		_ = SetHTTPTimeout(maxSanePostDuration)
-	}
+	httpClient, clientSource := r.currentHTTPClient()
 
 	for {
 		select {
@@ -57,19 +154,55 @@ func postLoop(ctx context.Context, metricsURL *url.URL, poster ErrorPoster) erro
 			return ctx.Err()
 		}
 
-		cht := currentHTTPTimeout()
-		if cht > maxSanePostDuration {
-			_ = SetHTTPTimeout(maxSanePostDuration)
-			cht = maxSanePostDuration
+		if newDuration := currentMetricsPostInterval(); newDuration != ourTickerDuration && newDuration > 0 {
+			// Takes effect from the next tick; this tick is already happening.
+			ourTickerDuration = newDuration
+			maxSanePostDuration = ourTickerDuration - time.Second
+			intervalTicker.Reset(ourTickerDuration)
+		}
+
+		if r.dedup != nil {
+			r.dedup.flush()
+		}
+
+		if r.httpClient == nil && loadHTTPClient() != clientSource {
+			httpClient, clientSource = r.currentHTTPClient()
 		}
-		if cht != httpClient.Timeout {
-			httpClient.Timeout = cht
+
+		// The timeout goes on each request's context rather than on the
+		// client, which belongs to the caller and may be shared.
+		r.postTimeout = currentHTTPTimeout()
+		if r.postTimeout > maxSanePostDuration && maxSanePostDuration > 0 {
+			_ = SetHTTPTimeout(maxSanePostDuration)
+			r.postTimeout = maxSanePostDuration
 		}
 
 		buf.Reset()
-		pauseTotalNS, numGC, err = gatherMetrics(&buf, pauseTotalNS, numGC)
-		if err != nil {
-			poster(err)
+		collected := time.Now()
+		var metrics Metrics
+		metrics, r.baseline.pauseTotalNS, r.baseline.numGC = gatherMetrics(r.baseline.pauseTotalNS, r.baseline.numGC)
+		if r.autoMemoryFraction > 0 {
+			memoryLimitGauge(&metrics)
+		}
+		r.runCollectors(&metrics)
+		if r.watchdog != nil {
+			r.watchdog.check(&metrics, r.poster)
+		}
+		if r.history != nil {
+			r.history.add(collected, metrics)
+		}
+		if err = encodeMetrics(&buf, metrics); err != nil {
+			r.poster(err)
+			continue
+		}
+
+		if r.mode == ModeDryRun {
+			r.reportDryRun(DryRunPayload{
+				URL:       r.redacted,
+				Payload:   append([]byte(nil), buf.Bytes()...),
+				Collected: collected,
+			})
+			r.postSucceeded()
 			continue
 		}
 
@@ -78,30 +211,49 @@ func postLoop(ctx context.Context, metricsURL *url.URL, poster ErrorPoster) erro
 		// perfectly regular interval and I don't think Heroku's metrics are at
 		// fine enough resolution for it to matter.
 		// For now, match Heroku, no sleep.
-		if err = submitMetrics(ctx, httpClient, &buf, metricsURL); err != nil {
-			poster(err)
+		if err = r.submit(ctx, httpClient, buf.Bytes()); err != nil {
+			r.poster(err)
+			if r.spool != nil {
+				r.spool.store(buf.Bytes(), collected, err, r.poster)
+			}
+			continue
+		}
+		r.postSucceeded()
+		if r.spool != nil {
+			// Leave at least half the interval clear for the next post.
+			r.spool.replay(ctx, r, httpClient, collected.Add(ourTickerDuration/2))
 		}
 	}
 }
 
-// This is currently copied unmodified from Heroku's code so is under their
-// (Salesforce's) copyright, as noted at the top of this file, unless (as noted
-// there) it's under Coda Hale's copyright.
+// Metrics is one interval's worth of metrics, in the form which we post.
+// Counters hold the change over the interval, Gauges the value at its end.
+type Metrics struct {
+	Counters map[string]float64 `json:"counters"`
+	Gauges   map[string]float64 `json:"gauges"`
+}
+
+// encodeMetrics writes the payload for m.
+func encodeMetrics(w io.Writer, m Metrics) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// This is copied from Heroku's code so is under their (Salesforce's)
+// copyright, as noted at the top of this file, unless (as noted there) it's
+// under Coda Hale's copyright.  The only modification is that we return the
+// result, rather than encoding it, so that other metrics can be added.
 //
 // We pretty much have to copy/paste, because this is the interface schema for
 // talking to their service and the code is the _only_ public documentation (at
 // time of writing) of what needs to be posted, so this has to match precisely.
 //
 // Salesforce-Copyright: {{{
-func gatherMetrics(w io.Writer, prevPauseTotalNS uint64, prevNumGC uint32) (uint64, uint32, error) {
+func gatherMetrics(prevPauseTotalNS uint64, prevNumGC uint32) (Metrics, uint64, uint32) {
 	var stats runtime.MemStats
 	runtime.ReadMemStats(&stats)
 
 	// cribbed from https://github.com/codahale/metrics/blob/master/runtime/memstats.go
-	result := struct {
-		Counters map[string]float64 `json:"counters"`
-		Gauges   map[string]float64 `json:"gauges"`
-	}{
+	result := Metrics{
 		Counters: map[string]float64{
 			"go.gc.collections": float64(stats.NumGC - prevNumGC),
 			"go.gc.pause.ns":    float64(stats.PauseTotalNs - prevPauseTotalNS),
@@ -115,18 +267,45 @@ func gatherMetrics(w io.Writer, prevPauseTotalNS uint64, prevNumGC uint32) (uint
 		},
 	}
 
-	return stats.PauseTotalNs, stats.NumGC, json.NewEncoder(w).Encode(result)
+	return result, stats.PauseTotalNs, stats.NumGC
 }
 
 // Salesforce-Copyright: }}}
 
+// submit posts one payload, applying this runner's options to the request.
+func (r *runner) submit(ctx context.Context, client *http.Client, payload []byte) error {
+	return r.submitWithHeader(ctx, client, payload, make(http.Header))
+}
+
+// submitWithHeader is submit, for when the caller has headers of its own to add.
+func (r *runner) submitWithHeader(ctx context.Context, client *http.Client, payload []byte, header http.Header) error {
+	wire := payload
+	if r.gzip {
+		if err := gzipPayload(&r.gzipBuf, payload); err != nil {
+			return err
+		}
+		wire = r.gzipBuf.Bytes()
+		header.Set("Content-Encoding", "gzip")
+	}
+	if r.postTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.postTimeout)
+		defer cancel()
+	}
+	if err := r.addAuthHeaders(ctx, header, wire); err != nil {
+		return r.scrubError(err)
+	}
+	return r.scrubError(submitMetrics(ctx, client, bytes.NewReader(wire), r.metricsURL, header))
+}
+
 // This was also copy/paste but this is also so formulaic that it's what anyone
 // would have written anyway.  The only point to decide is what value to use
 // for the Content-Type header.  Plus how to construct the error, which we did
 // actually change.  And we adjusted the req context pairing, to make this
 // closer to my style (associated the ctx ASAP to match conceptually those
-// functions which take a ctx when generating).  And added a User-Agent.
-func submitMetrics(ctx context.Context, client *http.Client, r io.Reader, metricsURL *url.URL) error {
+// functions which take a ctx when generating).  And added a User-Agent, and
+// the extra headers which our options call for.
+func submitMetrics(ctx context.Context, client *http.Client, r io.Reader, metricsURL *url.URL, extra http.Header) error {
 	req, err := http.NewRequest("POST", metricsURL.String(), r)
 	if err != nil {
 		return err
@@ -134,7 +313,11 @@ func submitMetrics(ctx context.Context, client *http.Client, r io.Reader, metric
 	req = req.WithContext(ctx)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("User-Agent", GetHTTPUserAgent())
+	for k, v := range extra {
+		req.Header[k] = v
+	}
 
+	started := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -142,14 +325,23 @@ func submitMetrics(ctx context.Context, client *http.Client, r io.Reader, metric
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		latency := time.Since(started)
 		safe, err := redactURL(metricsURL)
 		if err != nil {
 			safe = metricsURL
 		}
+		// Errors reading the body are not interesting: we already have a
+		// failure to report, and the snippet is only ever a hint.
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, MaxBodySnippetBytes))
 		return HTTPFailureError{
 			ExpectedResponseCode: http.StatusOK,
 			ActualResponseCode:   resp.StatusCode,
 			URL:                  safe.String(),
+			Status:               resp.Status,
+			BodySnippet:          strings.TrimSpace(strings.ToValidUTF8(string(snippet), "\uFFFD")),
+			RetryAfter:           resp.Header.Get("Retry-After"),
+			RequestID:            resp.Header.Get("X-Request-Id"),
+			Latency:              latency,
 		}
 	}
 
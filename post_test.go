@@ -0,0 +1,129 @@
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterBaselineSeeded(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		runtime.GC()
+	}
+	baseline := newCounterBaseline()
+	runtime.GC()
+	runtime.GC()
+
+	var result Metrics
+	result, baseline.pauseTotalNS, baseline.numGC = gatherMetrics(baseline.pauseTotalNS, baseline.numGC)
+	collections := result.Counters["go.gc.collections"]
+	if collections < 2 || collections >= 7 {
+		t.Errorf("go.gc.collections=%v, expected interval delta of about 2", collections)
+	}
+	if total := baseline.numGC; float64(total) <= collections {
+		t.Errorf("baseline not advanced: numGC=%d, delta=%v", total, collections)
+	}
+}
+
+func TestSubmitMetricsFailureDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "  overloaded, go away"+strings.Repeat(".", 2*MaxBodySnippetBytes))
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL + "/metrics?token=sekrit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = submitMetrics(context.Background(), ts.Client(), strings.NewReader("{}"), u, nil)
+	var failure HTTPFailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected HTTPFailureError, got: %v", err)
+	}
+	if failure.Status != "503 Service Unavailable" || failure.ActualResponseCode != 503 {
+		t.Errorf("bad status: %d %q", failure.ActualResponseCode, failure.Status)
+	}
+	if failure.RetryAfter != "120" || failure.RequestID != "abc-123" {
+		t.Errorf("headers not captured: retry-after=%q request-id=%q", failure.RetryAfter, failure.RequestID)
+	}
+	if !strings.HasPrefix(failure.BodySnippet, "overloaded, go away") || len(failure.BodySnippet) > MaxBodySnippetBytes {
+		t.Errorf("bad body snippet (%d bytes): %q", len(failure.BodySnippet), failure.BodySnippet)
+	}
+	if failure.Latency <= 0 {
+		t.Errorf("latency not recorded")
+	}
+	if msg := failure.Error(); strings.Contains(msg, "sekrit") || !strings.Contains(msg, "got 503 Service Unavailable instead of 200") {
+		t.Errorf("bad error message: %s", msg)
+	}
+}
+
+func TestPostLoopPicksUpChanges(t *testing.T) {
+	defer SetMetricsPostInterval(SetMetricsPostInterval(30 * time.Millisecond))
+	defer SetHTTPTimeout(SetHTTPTimeout(time.Second))
+	defer SetHTTPClient(loadHTTPClient())
+
+	// Each client records when it was used, on its own channel.
+	countingClient := func(used chan<- time.Time) *http.Client {
+		return &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			select {
+			case used <- time.Now():
+			default:
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})}
+	}
+	usedA := make(chan time.Time, 100)
+	usedB := make(chan time.Time, 100)
+	clientA := countingClient(usedA)
+	SetHTTPClient(clientA)
+
+	u, _ := url.Parse("http://metrics.invalid/")
+	r := &runner{metricsURL: u, poster: func(error) {}, mode: ModePosting, baseline: newCounterBaseline()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = postLoop(ctx, r)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case <-usedA:
+	case <-time.After(2 * time.Second):
+		t.Fatal("first client never used")
+	}
+
+	SetMetricsPostInterval(300 * time.Millisecond)
+	SetHTTPClient(countingClient(usedB))
+	// The tick which notices the change is already due on the old interval.
+	var first time.Time
+	select {
+	case first = <-usedB:
+	case <-time.After(2 * time.Second):
+		t.Fatal("new client never used")
+	}
+	select {
+	case second := <-usedB:
+		if gap := second.Sub(first); gap < 200*time.Millisecond {
+			t.Errorf("posts %s apart, expected the new interval of 300ms", gap)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no post on the new interval")
+	}
+	if clientA.Timeout != 0 {
+		t.Errorf("caller's client was modified: Timeout=%s", clientA.Timeout)
+	}
+}
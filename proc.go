@@ -0,0 +1,168 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procClockTicks is USER_HZ, the unit of the CPU times in /proc/self/stat.
+// It has been 100 on every Linux architecture which Go supports, and reading
+// it properly needs cgo.
+const procClockTicks = 100
+
+// NewProcessCollector returns a Collector of what the kernel charges the
+// process for, read from /proc/self, so is only useful on Linux:
+//
+//   - process.cpu.seconds (counter): user plus system CPU time
+//   - process.memory.rss.bytes (gauge): resident set size
+//   - process.threads (gauge): OS threads
+//   - process.fds.open (gauge): open file descriptors
+//   - process.fds.limit (gauge): the soft limit on open file descriptors
+func NewProcessCollector() Collector {
+	return newProcessCollector("/proc/self")
+}
+
+type processCollector struct {
+	dir        string
+	cpuSeconds float64
+	seeded     bool
+}
+
+func newProcessCollector(dir string) *processCollector {
+	c := &processCollector{dir: dir}
+	if cpu, err := c.readCPUSeconds(); err == nil {
+		c.cpuSeconds = cpu
+		c.seeded = true
+	}
+	return c
+}
+
+// Collect is the type-satisfying method which makes processCollector a
+// Collector.  Each source is independent, so we report what we can.
+func (c *processCollector) Collect(m *Metrics) error {
+	var problems []string
+
+	if cpu, err := c.readCPUSeconds(); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		if c.seeded {
+			m.Counters["process.cpu.seconds"] = cpu - c.cpuSeconds
+		}
+		c.cpuSeconds = cpu
+		c.seeded = true
+	}
+
+	if status, err := readProcKeyValues(filepath.Join(c.dir, "status")); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		if rss, ok := status["VmRSS"]; ok {
+			// "1792 kB"
+			if kb, err := strconv.ParseFloat(strings.TrimSuffix(rss, " kB"), 64); err == nil {
+				m.Gauges["process.memory.rss.bytes"] = kb * 1024
+			}
+		}
+		if threads, err := strconv.ParseFloat(status["Threads"], 64); err == nil {
+			m.Gauges["process.threads"] = threads
+		}
+	}
+
+	if fds, err := os.ReadDir(filepath.Join(c.dir, "fd")); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		m.Gauges["process.fds.open"] = float64(len(fds))
+	}
+
+	if limit, err := c.readFDLimit(); err != nil {
+		problems = append(problems, err.Error())
+	} else if limit >= 0 {
+		m.Gauges["process.fds.limit"] = limit
+	}
+
+	if problems != nil {
+		return fmt.Errorf("reading %s: %s", c.dir, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// readCPUSeconds returns utime+stime from the stat file.  The command name in
+// field 2 is parenthesized and may contain spaces, so we count fields from
+// after its closing parenthesis.
+func (c *processCollector) readCPUSeconds() (float64, error) {
+	stat, err := os.ReadFile(filepath.Join(c.dir, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed stat")
+	}
+	// fields[0] is field 3 (state), so utime (14) and stime (15) are 11 and 12
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("short stat")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("stat utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("stat stime: %w", err)
+	}
+	return float64(utime+stime) / procClockTicks, nil
+}
+
+// readFDLimit returns the soft "Max open files" limit, or -1 if unlimited.
+func (c *processCollector) readFDLimit() (float64, error) {
+	f, err := os.Open(filepath.Join(c.dir, "limits"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rest, ok := strings.CutPrefix(scanner.Text(), "Max open files")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return -1, nil
+		}
+		return strconv.ParseFloat(fields[0], 64)
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no open files limit in limits")
+}
+
+// readProcKeyValues parses files of "Key:\tvalue" lines, such as status.
+func readProcKeyValues(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			values[k] = strings.TrimSpace(v)
+		}
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,54 @@
+package hmetrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessCollectorFixture(t *testing.T) {
+	fixture := filepath.Join("testdata", "proc", "self")
+	dir := copyFixture(t, fixture)
+
+	c := newProcessCollector(dir)
+	m := newTestMetrics()
+	if err := c.Collect(&m); err != nil {
+		t.Fatalf("Collect: %s", err)
+	}
+	for name, want := range map[string]float64{
+		"process.memory.rss.bytes": 51200 * 1024,
+		"process.threads":          12,
+		"process.fds.open":         5,
+		"process.fds.limit":        10000,
+	} {
+		if have, ok := m.Gauges[name]; !ok || have != want {
+			t.Errorf("gauge %s=%v (present %v), expected %v", name, have, ok, want)
+		}
+	}
+	if have := m.Counters["process.cpu.seconds"]; have != 0 {
+		t.Errorf("first interval CPU delta %v, expected 0 from seeded baseline", have)
+	}
+
+	// utime 250 -> 400, stime 75 -> 125: 200 ticks more
+	stat, _ := os.ReadFile(filepath.Join(fixture, "stat"))
+	stat = []byte(strings.Replace(string(stat), " 250 75 ", " 400 125 ", 1))
+	if err := os.WriteFile(filepath.Join(dir, "stat"), stat, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m = newTestMetrics()
+	if err := c.Collect(&m); err != nil {
+		t.Fatalf("second Collect: %s", err)
+	}
+	if have := m.Counters["process.cpu.seconds"]; have != 2 {
+		t.Errorf("CPU delta %v, expected 2 seconds", have)
+	}
+}
+
+func TestProcessCollectorMissing(t *testing.T) {
+	c := newProcessCollector(filepath.Join(t.TempDir(), "nonexistent"))
+	m := newTestMetrics()
+	if err := c.Collect(&m); err == nil {
+		t.Error("expected error reading missing /proc tree")
+	}
+}
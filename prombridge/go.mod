@@ -0,0 +1,21 @@
+module go.pennock.tech/hmetrics/prombridge
+
+go 1.21
+
+require (
+	github.com/prometheus/client_golang v1.19.1
+	github.com/prometheus/client_model v0.6.1
+	// No tagged release of hmetrics has Collector and Metrics yet; until one
+	// does, this is resolved by the go.work at the top of the repository, and
+	// should be changed to require that release.
+	go.pennock.tech/hmetrics v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
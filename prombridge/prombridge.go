@@ -0,0 +1,269 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+// Package prombridge reports metrics from a Prometheus Gatherer, such as
+// prometheus.DefaultGatherer, as hmetrics metrics, so that code instrumented
+// for Prometheus need not be instrumented twice.
+//
+// It is a separate module so that hmetrics itself stays free of
+// dependencies.
+package prombridge
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"go.pennock.tech/hmetrics"
+)
+
+// DefaultPrefix is the metric name prefix used by New when the Config has an
+// empty Prefix.
+const DefaultPrefix = "prometheus"
+
+// DefaultQuantiles are estimated from histograms when Config.Quantiles is nil.
+var DefaultQuantiles = []float64{0.5, 0.95, 0.99}
+
+// Config controls how New converts metrics.
+type Config struct {
+	Prefix string
+	// Quantiles are estimated from each histogram's buckets, over the
+	// interval.  Summaries carry their own quantiles, which are used as-is.
+	Quantiles []float64
+}
+
+// New returns an hmetrics.Collector of the metrics from g, gathered each
+// interval.  A metric's labels, sorted by name, become dotted components of
+// its name, so with the default prefix
+// http_requests_total{code="200",method="get"} is reported as
+// "prometheus.http_requests_total.code_200.method_get".
+//
+//   - Counters are reported as counters, holding the change over the
+//     interval; a series is first reported in the interval after it is
+//     first seen.
+//   - Gauges and untyped metrics are reported as gauges, skipping any which
+//     are NaN or infinite.
+//   - Summaries are reported as gauges for each quantile, named like
+//     ".p50" and ".p99_9", plus ".count" and ".sum" counters.
+//   - Histograms are reported as the same, with the quantiles estimated by
+//     linear interpolation within the buckets observed during the interval,
+//     and absent if there were no observations.
+//   - Gauge histograms are reported with the same names, but all as gauges
+//     of their current values.
+func New(g prometheus.Gatherer, cfg Config) hmetrics.Collector {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.Quantiles == nil {
+		cfg.Quantiles = DefaultQuantiles
+	}
+	return &collector{
+		gatherer:   g,
+		cfg:        cfg,
+		counters:   make(map[string]float64),
+		histograms: make(map[string][]uint64),
+	}
+}
+
+type collector struct {
+	gatherer prometheus.Gatherer
+	cfg      Config
+
+	// counters holds the cumulative value of each counter, and of each
+	// summary and histogram count and sum, as of the last collection.
+	counters map[string]float64
+	// histograms holds the cumulative bucket counts of each histogram as of
+	// the last collection.
+	histograms map[string][]uint64
+	// seen holds the names of the series in the current collection.
+	seen map[string]bool
+}
+
+// Collect is the type-satisfying method which makes collector an
+// hmetrics.Collector.  Gather can return some metrics along with an error,
+// so we report whatever we were given.
+func (c *collector) Collect(m *hmetrics.Metrics) error {
+	families, err := c.gatherer.Gather()
+	c.seen = make(map[string]bool)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := c.seriesName(family.GetName(), metric.GetLabel())
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				c.counter(m, name, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				gauge(m, name, metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				gauge(m, name, metric.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				for _, q := range s.GetQuantile() {
+					gauge(m, name+"."+quantileName(q.GetQuantile()), q.GetValue())
+				}
+				c.counter(m, name+".count", float64(s.GetSampleCount()))
+				c.counter(m, name+".sum", s.GetSampleSum())
+			case dto.MetricType_HISTOGRAM:
+				c.histogram(m, name, metric.GetHistogram())
+			case dto.MetricType_GAUGE_HISTOGRAM:
+				c.gaugeHistogram(m, name, metric.GetHistogram())
+			}
+		}
+	}
+	// Forget series which have gone away, so that a reappearance is not
+	// reported as a delta from long ago.
+	for name := range c.counters {
+		if !c.seen[name] {
+			delete(c.counters, name)
+		}
+	}
+	for name := range c.histograms {
+		if !c.seen[name] {
+			delete(c.histograms, name)
+		}
+	}
+	return err
+}
+
+var nameReplacer = strings.NewReplacer(".", "_", " ", "_", "/", "_", ":", "_")
+
+// seriesName gives the hmetrics name for one labelled series.
+func (c *collector) seriesName(family string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(c.cfg.Prefix)
+	b.WriteByte('.')
+	b.WriteString(nameReplacer.Replace(family))
+	sorted := append([]*dto.LabelPair(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	for _, label := range sorted {
+		b.WriteByte('.')
+		b.WriteString(nameReplacer.Replace(label.GetName()))
+		b.WriteByte('_')
+		b.WriteString(nameReplacer.Replace(label.GetValue()))
+	}
+	return b.String()
+}
+
+// gauge reports value, unless it is NaN or infinite, which Prometheus allows
+// but which can't be encoded as JSON.
+func gauge(m *hmetrics.Metrics, name string, value float64) {
+	if finite(value) {
+		m.Gauges[name] = value
+	}
+}
+
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// counter reports the change in a cumulative value since the last collection.
+func (c *collector) counter(m *hmetrics.Metrics, name string, value float64) {
+	previous, ok := c.counters[name]
+	c.counters[name] = value
+	c.seen[name] = true
+	if !ok {
+		return
+	}
+	if value < previous {
+		// The counter was reset, so all of it is new.
+		previous = 0
+	}
+	if delta := value - previous; finite(delta) {
+		m.Counters[name] = delta
+	}
+}
+
+// histogram reports the count and sum of h as counters, and the quantiles of
+// the observations made during the interval as gauges.
+func (c *collector) histogram(m *hmetrics.Metrics, name string, h *dto.Histogram) {
+	c.counter(m, name+".count", float64(h.GetSampleCount()))
+	c.counter(m, name+".sum", h.GetSampleSum())
+
+	// The buckets exclude the implicit +Inf one, which holds everything.
+	buckets := h.GetBucket()
+	cumulative := make([]uint64, len(buckets)+1)
+	for i, bucket := range buckets {
+		cumulative[i] = bucket.GetCumulativeCount()
+	}
+	cumulative[len(buckets)] = h.GetSampleCount()
+	c.seen[name] = true
+	previous, ok := c.histograms[name]
+	c.histograms[name] = cumulative
+	if !ok || len(previous) != len(cumulative) || len(buckets) == 0 {
+		return
+	}
+
+	counts := make([]uint64, len(cumulative))
+	for i := range cumulative {
+		if cumulative[i] < previous[i] {
+			// Reset; try again next interval.
+			return
+		}
+		counts[i] = cumulative[i] - previous[i]
+	}
+	if counts[len(counts)-1] == 0 {
+		return
+	}
+	for _, q := range c.cfg.Quantiles {
+		m.Gauges[name+"."+quantileName(q)] = bucketQuantile(q, buckets, counts)
+	}
+}
+
+// gaugeHistogram reports a histogram whose buckets go up and down, so holding
+// a current distribution rather than a running total: its count, sum and
+// quantiles are all reported as gauges of their current values.
+func (c *collector) gaugeHistogram(m *hmetrics.Metrics, name string, h *dto.Histogram) {
+	gauge(m, name+".count", float64(h.GetSampleCount()))
+	gauge(m, name+".sum", h.GetSampleSum())
+	buckets := h.GetBucket()
+	if len(buckets) == 0 || h.GetSampleCount() == 0 {
+		return
+	}
+	counts := make([]uint64, len(buckets)+1)
+	for i, bucket := range buckets {
+		counts[i] = bucket.GetCumulativeCount()
+	}
+	counts[len(buckets)] = h.GetSampleCount()
+	for _, q := range c.cfg.Quantiles {
+		gauge(m, name+"."+quantileName(q), bucketQuantile(q, buckets, counts))
+	}
+}
+
+// bucketQuantile estimates the q-quantile from per-interval cumulative bucket
+// counts, which end with the +Inf bucket, interpolating linearly within the
+// bucket in which it falls, in the manner of PromQL's histogram_quantile.
+// Anything in the +Inf bucket is reported as the highest finite bound.
+func bucketQuantile(q float64, buckets []*dto.Bucket, counts []uint64) float64 {
+	rank := q * float64(counts[len(counts)-1])
+	i := sort.Search(len(counts), func(i int) bool { return float64(counts[i]) >= rank })
+	if i >= len(buckets) {
+		return buckets[len(buckets)-1].GetUpperBound()
+	}
+	upper := buckets[i].GetUpperBound()
+	if math.IsInf(upper, 1) && i > 0 {
+		return buckets[i-1].GetUpperBound()
+	}
+	lower, below := 0.0, uint64(0)
+	if i > 0 {
+		lower, below = buckets[i-1].GetUpperBound(), counts[i-1]
+	} else if upper <= 0 {
+		return upper
+	}
+	inBucket := counts[i] - below
+	if inBucket == 0 {
+		return upper
+	}
+	return lower + (upper-lower)*(rank-float64(below))/float64(inBucket)
+}
+
+// quantileName gives the metric name suffix for q, such as "p99" for 0.99
+// or "p99_9" for 0.999.
+func quantileName(q float64) string {
+	// Rounding avoids names like p94_99999999999999 from float error.
+	percent := math.Round(q*1e6) / 1e4
+	return "p" + strings.ReplaceAll(strconv.FormatFloat(percent, 'f', -1, 64), ".", "_")
+}
@@ -0,0 +1,154 @@
+package prombridge
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"go.pennock.tech/hmetrics"
+)
+
+func newMetrics() hmetrics.Metrics {
+	return hmetrics.Metrics{Counters: map[string]float64{}, Gauges: map[string]float64{}}
+}
+
+func TestCollector(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+	}, []string{"method", "code"})
+	inflight := prometheus.NewGauge(prometheus.GaugeOpts{Name: "inflight"})
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "latency_seconds",
+		Buckets: []float64{0.1, 0.2, 0.4, 0.8},
+	})
+	sizes := prometheus.NewSummary(prometheus.SummaryOpts{
+		Name:       "size_bytes",
+		Objectives: map[float64]float64{0.5: 0.05, 0.999: 0.0001},
+	})
+	reg.MustRegister(requests, inflight, latency, sizes)
+
+	c := New(reg, Config{})
+
+	requests.WithLabelValues("get", "200").Add(10)
+	latency.Observe(0.05)
+	m := newMetrics()
+	if err := c.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Counters["prometheus.http_requests_total.code_200.method_get"]; ok {
+		t.Errorf("counter reported in the interval it was first seen")
+	}
+	if _, ok := m.Gauges["prometheus.latency_seconds.p50"]; ok {
+		t.Errorf("histogram quantile reported in the interval it was first seen")
+	}
+
+	requests.WithLabelValues("get", "200").Add(5)
+	inflight.Set(3)
+	for i := 0; i < 10; i++ {
+		latency.Observe(0.3)
+	}
+	sizes.Observe(100)
+	m = newMetrics()
+	if err := c.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{
+		"prometheus.http_requests_total.code_200.method_get": 5,
+		"prometheus.latency_seconds.count":                   10,
+		"prometheus.latency_seconds.sum":                     3,
+	} {
+		if have := m.Counters[name]; math.Abs(have-want) > 1e-9 {
+			t.Errorf("counter %s=%v, expected %v", name, have, want)
+		}
+	}
+	for name, want := range map[string]float64{
+		"prometheus.inflight": 3,
+		// all ten this interval fell in (0.2, 0.4]
+		"prometheus.latency_seconds.p50": 0.3,
+		"prometheus.latency_seconds.p99": 0.398,
+		"prometheus.size_bytes.p50":      100,
+		"prometheus.size_bytes.p99_9":    100,
+	} {
+		if have := m.Gauges[name]; math.Abs(have-want) > 1e-9 {
+			t.Errorf("gauge %s=%v, expected %v", name, have, want)
+		}
+	}
+
+	m = newMetrics()
+	_ = c.Collect(&m)
+	if _, ok := m.Gauges["prometheus.latency_seconds.p50"]; ok {
+		t.Errorf("histogram quantile reported for a quiet interval")
+	}
+	if have := m.Counters["prometheus.http_requests_total.code_200.method_get"]; have != 0 {
+		t.Errorf("counter delta %v for a quiet interval", have)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestNonFiniteAndGaugeHistogram(t *testing.T) {
+	queued := uint64(4)
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return []*dto.MetricFamily{
+			{
+				Name: ptr("ratio"),
+				Type: dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{
+					{Gauge: &dto.Gauge{Value: ptr(math.NaN())}},
+					{Label: []*dto.LabelPair{{Name: ptr("x"), Value: ptr("inf")}}, Gauge: &dto.Gauge{Value: ptr(math.Inf(1))}},
+				},
+			},
+			{
+				Name: ptr("queue_age"),
+				Type: dto.MetricType_GAUGE_HISTOGRAM.Enum(),
+				Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+					SampleCount: ptr(queued),
+					SampleSum:   ptr(float64(queued)),
+					Bucket: []*dto.Bucket{
+						{UpperBound: ptr(1.0), CumulativeCount: ptr(queued / 2)},
+						{UpperBound: ptr(2.0), CumulativeCount: ptr(queued)},
+					},
+				}}},
+			},
+		}, nil
+	})
+	c := New(gatherer, Config{Quantiles: []float64{0.5}})
+	for _, n := range []uint64{4, 2} {
+		queued = n
+		m := newMetrics()
+		if err := c.Collect(&m); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Counters) != 0 {
+			t.Errorf("gauge histogram reported counters: %v", m.Counters)
+		}
+		for name, want := range map[string]float64{
+			"prometheus.queue_age.count": float64(n),
+			"prometheus.queue_age.sum":   float64(n),
+			"prometheus.queue_age.p50":   1,
+		} {
+			if have, ok := m.Gauges[name]; !ok || have != want {
+				t.Errorf("with %d queued, gauge %s=%v, expected %v", n, name, have, want)
+			}
+		}
+		if len(m.Gauges) != 3 {
+			t.Errorf("non-finite gauges reported: %v", m.Gauges)
+		}
+	}
+}
+
+func TestQuantileName(t *testing.T) {
+	for q, want := range map[float64]string{
+		0.5:   "p50",
+		0.95:  "p95",
+		0.99:  "p99",
+		0.999: "p99_9",
+	} {
+		if have := quantileName(q); have != want {
+			t.Errorf("quantileName(%v)=%q, expected %q", q, have, want)
+		}
+	}
+}
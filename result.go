@@ -0,0 +1,118 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reason says why Start did, or did not, start the metrics poster.
+type Reason int
+
+// These are the Reason values which Start can report.
+const (
+	// ReasonUnknown is the zero value, which Start never reports, so that an
+	// unset StartResult can't be mistaken for one which started.
+	ReasonUnknown Reason = iota
+	// ReasonStarted means that we did start; see StartResult.Mode for how.
+	ReasonStarted
+	// ReasonMissingPoster means the ErrorPoster was nil; see ErrMissingPoster.
+	ReasonMissingPoster
+	// ReasonEnvMissing means that no endpoint was found in environ.
+	ReasonEnvMissing
+	// ReasonEnvEmpty means that the endpoint variable was present but empty.
+	ReasonEnvEmpty
+	// ReasonDisabled means that EnvKeyDisable told us not to start.
+	ReasonDisabled
+	// ReasonBadEnvironment means that an HMETRICS_* variable was invalid.
+	ReasonBadEnvironment
+	// ReasonBadURL means that the endpoint could not be used.
+	ReasonBadURL
+	// ReasonBadOption means that an Option was given unusable values.
+	ReasonBadOption
+)
+
+// String gives a short stable label for the reason, suitable for use as a
+// structured logging value.
+func (r Reason) String() string {
+	switch r {
+	case ReasonUnknown:
+		return "unknown"
+	case ReasonStarted:
+		return "started"
+	case ReasonMissingPoster:
+		return "missing-poster"
+	case ReasonEnvMissing:
+		return "env-missing"
+	case ReasonEnvEmpty:
+		return "env-empty"
+	case ReasonDisabled:
+		return "disabled"
+	case ReasonBadEnvironment:
+		return "bad-environment"
+	case ReasonBadURL:
+		return "bad-url"
+	case ReasonBadOption:
+		return "bad-option"
+	default:
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+}
+
+// Settings is a snapshot of the tunables in effect, after the environment
+// has been applied.
+type Settings struct {
+	PostInterval             time.Duration
+	HTTPTimeout              time.Duration
+	MaxFailureBackoff        time.Duration
+	ResetFailureBackoffAfter time.Duration
+	ResetFailureBackoffTo    time.Duration
+	UserAgent                string
+	// DryRun is as set by SetDryRun, except that for a poster which started
+	// it is whether that poster is in dry-run mode, which EnvKeyDryRun and
+	// WithDryRun also decide.
+	DryRun bool
+	// AutoMemoryLimit is the fraction of the memory quota to which the
+	// runtime memory limit is set, or 0 if that is not enabled; in a
+	// StartResult it includes WithAutoMemoryLimit.
+	AutoMemoryLimit float64
+}
+
+func currentSettings() Settings {
+	return Settings{
+		PostInterval:             currentMetricsPostInterval(),
+		HTTPTimeout:              currentHTTPTimeout(),
+		MaxFailureBackoff:        currentMaxFailureBackoff(),
+		ResetFailureBackoffAfter: currentResetFailureBackoffAfter(),
+		ResetFailureBackoffTo:    currentResetFailureBackoffTo(),
+		UserAgent:                GetHTTPUserAgent(),
+		DryRun:                   currentDryRun(),
+		AutoMemoryLimit:          currentEnvAutoMemoryFraction(),
+	}
+}
+
+// StartResult is the machine-readable account of what Start did.
+type StartResult struct {
+	// Started is true if the poster go-routine is running.
+	Started bool
+	Reason  Reason
+	Mode    Mode
+	// Endpoint is the redacted form of the URL we post to; it is empty if
+	// we did not start, or are in dry-run mode without an endpoint.
+	Endpoint string
+	// EndpointEnvKey is the environment variable from which Endpoint came.
+	EndpointEnvKey string
+	// Headers names the extra request headers which options add to each
+	// post; their values are deliberately not reported.
+	Headers []string
+	// MemoryLimit is the runtime memory limit which WithAutoMemoryLimit
+	// set, or zero if it set none.
+	MemoryLimit int64
+	Settings    Settings
+	// Message is the human-readable summary which Spawn returns as its
+	// logMessage.
+	Message string
+}
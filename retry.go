@@ -9,7 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"net/url"
 	"time"
 )
 
@@ -31,17 +30,46 @@ func isDeadContext(ctx context.Context) bool {
 	}
 }
 
-// retryPostLoop should be the top function in a new go-routine
-func retryPostLoop(ctx context.Context, u *url.URL, poster ErrorPoster) {
+// BackoffError is passed to the ErrorPoster when the metrics-posting loop has
+// exited and we are about to wait before restarting it.
+type BackoffError struct {
+	// Backoff is how long we will wait before restarting.
+	Backoff time.Duration
+	// Attempt counts the restarts since the loop was last healthy; the
+	// first is 1.
+	Attempt int
+	// Lasted is how long the loop ran before exiting.
+	Lasted time.Duration
+	// Err is why the loop exited.
+	Err error
+}
+
+// Error is the type-satisfying method which lets a BackoffError be an "error".
+func (e BackoffError) Error() string {
+	return fmt.Sprintf("sleeping %.2fs: %s", float64(e.Backoff)/float64(time.Second), e.Err)
+}
+
+// Unwrap gives access to the reason for the loop exiting.
+func (e BackoffError) Unwrap() error {
+	return e.Err
+}
+
+// retryPostLoop should be the top function in a new go-routine.
+// The runner, including its counter baseline, is carried across restarts of
+// postLoop.
+func retryPostLoop(ctx context.Context, r *runner) {
+	poster := r.poster
+	attempt := 0
 	for backoff := currentResetFailureBackoffTo(); ; backoff = raiseBackoff(backoff) {
 		var err error
+		attempt++
 		if isDeadContext(ctx) {
 			poster(ctx.Err())
 			return
 		}
 
 		startLatest := time.Now()
-		err = postLoop(ctx, u, poster)
+		err = postLoop(ctx, r)
 		duration := time.Since(startLatest)
 
 		if err == nil {
@@ -52,6 +80,7 @@ func retryPostLoop(ctx context.Context, u *url.URL, poster ErrorPoster) {
 
 		if duration >= currentResetFailureBackoffAfter() {
 			backoff = currentResetFailureBackoffTo()
+			attempt = 1
 		}
 		max := currentMaxFailureBackoff()
 		if backoff > max {
@@ -63,7 +92,12 @@ func retryPostLoop(ctx context.Context, u *url.URL, poster ErrorPoster) {
 			return
 		}
 
-		poster(fmt.Errorf("sleeping %.2fs: %w", float64(backoff)/float64(time.Second), err))
+		poster(BackoffError{
+			Backoff: backoff,
+			Attempt: attempt,
+			Lasted:  duration,
+			Err:     err,
+		})
 
 		timer := time.NewTimer(backoff)
 		select {
@@ -0,0 +1,96 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRouterQueuePrefix is the metric name prefix used by
+// NewRouterQueueMetrics when given an empty prefix.
+const DefaultRouterQueuePrefix = "heroku.router.queue"
+
+// RouterQueueMetrics measures how long requests waited between Heroku's
+// router and your application, using the X-Request-Start header which the
+// router sets, and is a Collector reporting that each interval.  This is the
+// most useful signal for autoscaling web dynos.  With the default prefix, the
+// metrics are the gauges heroku.router.queue.p50.ms, .p95.ms, .p99.ms and
+// .max.ms, absent in intervals without any requests bearing the header.
+//
+// Put its Middleware as far out as you can, so that the measurement is not
+// inflated by the time taken in your own middleware.  Use this together with
+// HTTPServerMetrics, which measures the time after the request reaches you.
+type RouterQueueMetrics struct {
+	prefix string
+	queue  sampleWindow
+	now    func() time.Time
+}
+
+// NewRouterQueueMetrics returns a RouterQueueMetrics reporting under prefix,
+// or DefaultRouterQueuePrefix if that's empty.
+func NewRouterQueueMetrics(prefix string) *RouterQueueMetrics {
+	if prefix == "" {
+		prefix = DefaultRouterQueuePrefix
+	}
+	return &RouterQueueMetrics{prefix: prefix, now: time.Now}
+}
+
+// Middleware wraps next, measuring the queue time of each request first.
+func (q *RouterQueueMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if start, ok := parseRequestStart(req.Header.Get("X-Request-Start")); ok {
+			wait := q.now().Sub(start)
+			if wait < 0 {
+				// clock skew between router and dyno
+				wait = 0
+			}
+			q.queue.observe(float64(wait) / float64(time.Millisecond))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Collect is the type-satisfying method which makes RouterQueueMetrics a
+// Collector.
+func (q *RouterQueueMetrics) Collect(m *Metrics) error {
+	addPercentileGauges(m, &q.queue, q.prefix, ".ms")
+	return nil
+}
+
+// parseRequestStart handles X-Request-Start as set by Heroku (milliseconds
+// since the epoch) and, for use behind other proxies, the `t=` prefixed
+// forms used by nginx (seconds, with fraction) and others (microseconds).
+func parseRequestStart(header string) (time.Time, bool) {
+	header = strings.TrimPrefix(strings.TrimSpace(header), "t=")
+	if header == "" {
+		return time.Time{}, false
+	}
+	if strings.Contains(header, ".") {
+		secs, err := strconv.ParseFloat(header, 64)
+		if err != nil || secs <= 0 {
+			return time.Time{}, false
+		}
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)), true
+	}
+	n, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case n > 1e17:
+		return time.Unix(0, n), true
+	case n > 1e14:
+		return time.UnixMicro(n), true
+	case n > 1e11:
+		return time.UnixMilli(n), true
+	default:
+		return time.Unix(n, 0), true
+	}
+}
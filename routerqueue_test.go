@@ -0,0 +1,60 @@
+package hmetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseRequestStart(t *testing.T) {
+	want := time.Date(2026, 7, 8, 9, 10, 11, 250_000_000, time.UTC)
+	for i, header := range []string{
+		"1783501811250",
+		"t=1783501811250000",
+		"t=1783501811.250",
+		" 1783501811250 ",
+	} {
+		have, ok := parseRequestStart(header)
+		if !ok || !have.Equal(want) {
+			t.Errorf("[%d] parseRequestStart(%q)=%v,%v; expected %v", i, header, have, ok, want)
+		}
+	}
+	for _, header := range []string{"", "soon", "t=", "-5"} {
+		if _, ok := parseRequestStart(header); ok {
+			t.Errorf("parseRequestStart(%q) accepted junk", header)
+		}
+	}
+}
+
+func TestRouterQueueMetrics(t *testing.T) {
+	q := NewRouterQueueMetrics("")
+	now := time.Date(2026, 7, 8, 9, 10, 11, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	handler := q.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, delay := range []time.Duration{10, 20, 30, 40, -5} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-Start", formatMillis(now.Add(-delay*time.Millisecond)))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	m := newTestMetrics()
+	if err := q.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{
+		"heroku.router.queue.p50.ms": 20,
+		"heroku.router.queue.max.ms": 40,
+	} {
+		if m.Gauges[name] != want {
+			t.Errorf("gauge %s=%v, expected %v", name, m.Gauges[name], want)
+		}
+	}
+}
+
+func formatMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+)
+
+// NewSlogPoster returns an ErrorPoster which logs each error to logger, with
+// a level and attributes chosen according to what kind of error it is:
+//
+//   - problems posting to the endpoint, which should be transient, at Warn
+//   - notices of backoff, cancellation and dry-run payloads at Info
+//   - configuration problems at Error
+//
+// Anything we don't recognize is logged at Warn.  URLs are always redacted.
+func NewSlogPoster(logger *slog.Logger) ErrorPoster {
+	return func(e error) {
+		level, msg, attrs := SlogAttrs(e)
+		logger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
+// SlogAttrs is the classification used by NewSlogPoster, exposed so that it
+// can also be used for the error returned by Spawn, or in your own ErrorPoster.
+func SlogAttrs(e error) (level slog.Level, msg string, attrs []slog.Attr) {
+	var (
+		httpFailure HTTPFailureError
+		backoff     BackoffError
+		dryRun      DryRunPayload
+		repeated    RepeatedError
+		recovered   RecoveredNotice
+		memory      MemoryWarning
+		envErr      EnvError
+		invalidURL  InvalidURLError
+		urlErr      *url.Error
+	)
+	attrs = []slog.Attr{slog.String("error", e.Error())}
+
+	switch {
+	case errors.As(e, &dryRun):
+		return slog.LevelInfo, "hmetrics: dry-run payload", []slog.Attr{
+			slog.String("url", dryRun.URL),
+			slog.String("payload", string(dryRun.Payload)),
+		}
+	case errors.As(e, &recovered):
+		return slog.LevelInfo, "hmetrics: posting recovered", []slog.Attr{
+			slog.Int("failures", recovered.Failures),
+			slog.Duration("outage", recovered.Recovered.Sub(recovered.Since)),
+		}
+	case errors.As(e, &memory):
+		return slog.LevelWarn, "hmetrics: memory use past threshold", append(attrs,
+			slog.Float64("threshold", memory.Threshold),
+			slog.Int64("used", memory.Used),
+			slog.String("source", memory.Source),
+			slog.Int64("quota", memory.Quota),
+			slog.String("profile", memory.ProfilePath),
+		)
+	case errors.As(e, &repeated):
+		level, _, lastAttrs := SlogAttrs(repeated.Last)
+		return level, "hmetrics: repeated failures", append(lastAttrs,
+			slog.Int("count", repeated.Count),
+			slog.Duration("period", repeated.Period),
+		)
+	case errors.As(e, &backoff):
+		return slog.LevelInfo, "hmetrics: backing off before restarting", append(attrs,
+			slog.Duration("backoff", backoff.Backoff),
+			slog.Int("attempt", backoff.Attempt),
+			slog.Duration("lasted", backoff.Lasted),
+		)
+	case errors.As(e, &httpFailure):
+		return slog.LevelWarn, "hmetrics: metrics post failed", append(attrs,
+			slog.String("url", httpFailure.URL),
+			slog.Int("status", httpFailure.ActualResponseCode),
+			slog.Int("expected_status", httpFailure.ExpectedResponseCode),
+			slog.String("status_text", httpFailure.Status),
+			slog.Duration("latency", httpFailure.Latency),
+			slog.String("request_id", httpFailure.RequestID),
+			slog.String("retry_after", httpFailure.RetryAfter),
+			slog.String("body", httpFailure.BodySnippet),
+		)
+	case errors.As(e, &envErr):
+		return slog.LevelError, "hmetrics: configuration problem", append(attrs,
+			slog.String("env", envErr.Key),
+		)
+	case errors.As(e, &invalidURL), errors.Is(e, ErrMissingPoster):
+		return slog.LevelError, "hmetrics: configuration problem", attrs
+	case errors.As(e, &urlErr):
+		// The error text from net/http includes the URL, unredacted.
+		attrs[0] = slog.String("error", urlErr.Err.Error())
+		return slog.LevelWarn, "hmetrics: metrics post failed", append(attrs,
+			slog.String("url", redactURLString(urlErr.URL)),
+			slog.Bool("timeout", urlErr.Timeout()),
+		)
+	case errors.Is(e, context.Canceled):
+		return slog.LevelInfo, "hmetrics: stopped", attrs
+	default:
+		return slog.LevelWarn, "hmetrics: error", attrs
+	}
+}
+
+// redactURLString is redactURL for when all we have is a string, and we'd
+// rather lose the URL than leak credentials.
+func redactURLString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "(unparseable)"
+	}
+	clean, err := redactURL(u)
+	if err != nil {
+		return "(unparseable)"
+	}
+	return clean.String()
+}
@@ -3,25 +3,53 @@
 package hmetrics
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 )
 
-func TestBasicSending(t *testing.T) {
-	var receivedAtomic uint64
-	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// standInServer is a local stand-in for Heroku's endpoint, which counts the
+// posts which it can decode.
+func standInServer(t *testing.T, receivedAtomic *uint64) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		_, _ = io.ReadAll(r.Body)
-		atomic.AddUint64(&receivedAtomic, 1)
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		var payload struct {
+			Counters map[string]float64 `json:"counters"`
+			Gauges   map[string]float64 `json:"gauges"`
+		}
+		if err := json.NewDecoder(body).Decode(&payload); err != nil || payload.Gauges == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddUint64(receivedAtomic, 1)
 	}))
+}
+
+func TestBasicSending(t *testing.T) {
+	var receivedAtomic uint64
+	ts := standInServer(t, &receivedAtomic)
+	defer ts.Close()
 
 	os.Setenv(EnvKeyEndpoint, ts.URL)
 
@@ -32,11 +60,86 @@ func TestBasicSending(t *testing.T) {
 	SetResetFailureBackoffAfter(2 * time.Second)
 	SetHTTPClient(ts.Client())
 
-	Spawn(func(e error) { t.Error(e) })
+	// the poster will be told of the cancellation after we return, so must not touch t
+	errs := make(chan error, 10)
+	_, cancel, err := Spawn(func(e error) {
+		select {
+		case errs <- e:
+		default:
+		}
+	})
+	if err != nil || cancel == nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
 	time.Sleep(3 * time.Second)
+	select {
+	case e := <-errs:
+		t.Errorf("poster got an error: %s", e)
+	default:
+	}
+	cancel()
 	received := atomic.LoadUint64(&receivedAtomic)
 	t.Logf("server received %d requests", received)
 	if received == 0 {
 		t.Fail()
 	}
 }
+
+func TestGzipSending(t *testing.T) {
+	var receivedAtomic uint64
+	ts := standInServer(t, &receivedAtomic)
+	defer ts.Close()
+
+	defer SetMetricsPostInterval(SetMetricsPostInterval(1100 * time.Millisecond))
+	defer SetHTTPTimeout(SetHTTPTimeout(100 * time.Millisecond))
+	defer SetHTTPClient(loadHTTPClient())
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 10)
+	r := &runner{metricsURL: u, poster: func(e error) { errs <- e }, mode: ModePosting}
+	WithGzip()(r)
+	SetHTTPClient(ts.Client())
+
+	_, cancel, _ := r.start(context.Background(), "test")
+	time.Sleep(2500 * time.Millisecond)
+	select {
+	case e := <-errs:
+		t.Errorf("poster got an error: %s", e)
+	default:
+	}
+	cancel()
+	if received := atomic.LoadUint64(&receivedAtomic); received == 0 {
+		t.Error("server decoded no gzipped posts")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	os.Unsetenv(EnvKeyEndpoint)
+	defer SetMetricsPostInterval(SetMetricsPostInterval(1100 * time.Millisecond))
+	defer SetDryRun(SetDryRun(true))
+
+	payloads := make(chan DryRunPayload, 4)
+	SetDryRunReporter(func(p DryRunPayload) { payloads <- p })
+	defer SetDryRunReporter(nil)
+
+	// the poster will be told of the cancellation after we return, so must not touch t
+	result, cancel, err := Start(func(e error) {})
+	if err != nil {
+		t.Fatalf("Start failed: %s", err)
+	}
+	if result.Mode != ModeDryRun || cancel == nil {
+		t.Fatalf("expected to start in dry-run mode, got mode %v: %s", result.Mode, result.Message)
+	}
+	defer cancel()
+
+	select {
+	case p := <-payloads:
+		if !strings.Contains(string(p.Payload), `"go.routines"`) {
+			t.Errorf("dry-run payload missing expected gauge: %s", p.Payload)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("no dry-run payload reported")
+	}
+}
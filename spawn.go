@@ -9,9 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // InvalidURLError is an error type, indicating that we could not handle the
@@ -34,32 +34,88 @@ func (e InvalidURLError) Error() string {
 // that's your decision and one which should be explicit in your code.
 var ErrMissingPoster = errors.New("hmetrics: given a nil poster callback")
 
-func realSpawn(poster ErrorPoster) (logMessage string, cancel func(), err error) {
+func realSpawn(parent context.Context, poster ErrorPoster, opts []Option) (result StartResult, cancel func(), err error) {
+	if parent == nil {
+		parent = context.Background()
+	}
 	if poster == nil {
-		return "hmetrics: not starting stats export, given no poster", nil, ErrMissingPoster
+		return notStarted(ReasonMissingPoster, "hmetrics: not starting stats export, given no poster"), nil, ErrMissingPoster
+	}
+
+	// The kill-switch comes first, so that it works even when something
+	// else in the environment is broken, and so that we change nothing.
+	disabled, err := disabledByEnvironment()
+	if err != nil {
+		return notStarted(ReasonBadEnvironment, "hmetrics: not starting stats export, bad environment configuration"), nil, err
+	}
+	if disabled {
+		result = notStarted(ReasonDisabled, "hmetrics: not starting stats export, disabled by '"+EnvKeyDisable+"'")
+		result.Mode = ModeDisabled
+		return result, nil, nil
+	}
+	env, err := applyEnvironment()
+	if err != nil {
+		return notStarted(ReasonBadEnvironment, "hmetrics: not starting stats export, bad environment configuration"), nil, err
 	}
 
-	target, ok := os.LookupEnv(EnvKeyEndpoint)
-	commonFailurePrefix := "hmetrics: not starting stats export, '" + EnvKeyEndpoint + "' "
-	if !ok {
-		return commonFailurePrefix + "not found in environ", nil, nil
+	r := &runner{poster: poster, mode: ModePosting}
+	dryRun := currentDryRun()
+	if env.dryRun != nil {
+		dryRun = *env.dryRun
 	}
-	if target == "" {
-		return commonFailurePrefix + "is empty", nil, nil
+	if dryRun {
+		r.mode = ModeDryRun
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if fraction := currentEnvAutoMemoryFraction(); fraction > 0 {
+		r.autoMemoryFraction = fraction
+	}
+	r.buildTLSClient()
+	if r.optionErr != nil {
+		return notStarted(ReasonBadOption, "hmetrics: not starting stats export, bad option"), nil, r.optionErr
+	}
+	if r.summaryWindow > 0 {
+		r.dedup = newErrorDeduper(r.summaryWindow, r.poster)
+		r.poster = r.dedup.post
+	}
+	if h := r.history; h != nil {
+		// outermost, so that the history sees every error, even those
+		// which the deduper will suppress
+		next := r.poster
+		r.poster = func(e error) {
+			h.recordError(time.Now(), e)
+			next(e)
+		}
+	}
+
+	envKey, target, ok := lookupEndpoint()
+	commonFailurePrefix := "hmetrics: not starting stats export, '" + envKey + "' "
+	switch {
+	case ok && target != "":
+	case r.mode == ModeDryRun:
+		// Dry-run is useful in local development, where there is no endpoint.
+		return r.start(parent, "hmetrics: started stats export in dry-run mode, without an endpoint")
+	case !ok:
+		return notStarted(ReasonEnvMissing, commonFailurePrefix+"not found in environ"), nil, nil
+	default:
+		return notStarted(ReasonEnvEmpty, commonFailurePrefix+"is empty"), nil, nil
+	}
+	r.envKey = envKey
 	u, err := url.Parse(target)
 	if err != nil {
-		return commonFailurePrefix + "could not be parsed", nil, err
+		return notStarted(ReasonBadURL, commonFailurePrefix+"could not be parsed"), nil, err
 	}
 	switch u.Scheme {
 	case "http", "https":
 	default:
-		return commonFailurePrefix + "has invalid URL scheme", nil, InvalidURLError{scheme: u.Scheme}
+		return notStarted(ReasonBadURL, commonFailurePrefix+"has invalid URL scheme"), nil, InvalidURLError{scheme: u.Scheme}
 	}
 
 	redacted, err := redactURL(u)
 	if err != nil {
-		return commonFailurePrefix + "is badly malformed", nil, err
+		return notStarted(ReasonBadURL, commonFailurePrefix+"is badly malformed"), nil, err
 	}
 
 	// caveat: the act of redacting will re-order any query params, so the form
@@ -70,10 +126,60 @@ func realSpawn(poster ErrorPoster) (logMessage string, cancel func(), err error)
 	// close to opaque as possible.  We're taking liberties by double-checking
 	// for auth information to redact.
 
-	ctx, cancel := context.WithCancel(context.Background())
+	r.metricsURL = u
+	r.redacted = redacted.String()
+	if r.mode == ModeDryRun {
+		return r.start(parent, fmt.Sprintf("hmetrics: started stats export in dry-run mode, not posting to %q", r.redacted))
+	}
+	return r.start(parent, fmt.Sprintf("hmetrics: started stats export to %q", r.redacted))
+}
+
+// notStarted builds the result for the many ways in which we decline to start.
+func notStarted(reason Reason, message string) StartResult {
+	return StartResult{
+		Reason:   reason,
+		Message:  message,
+		Settings: currentSettings(),
+	}
+}
 
-	go retryPostLoop(ctx, u, poster)
-	return fmt.Sprintf("hmetrics: started stats export to %q", redacted), cancel, nil
+// start launches the go-routine for a fully configured runner.
+func (r *runner) start(parent context.Context, message string) (StartResult, func(), error) {
+	if r.spoolConfig != nil {
+		s, err := openSpool(*r.spoolConfig)
+		if err != nil {
+			return notStarted(ReasonBadOption, "hmetrics: not starting stats export, cannot open spool"), nil, err
+		}
+		r.spool = s
+	}
+	if r.watchdog != nil {
+		if err := r.watchdog.makeProfileDir(); err != nil {
+			return notStarted(ReasonBadOption, "hmetrics: not starting stats export, cannot create heap profile directory"), nil, err
+		}
+	}
+	if r.autoMemoryFraction > 0 {
+		r.memoryLimit = applyAutoMemoryLimit(r.autoMemoryFraction, newCgroupFS(cgroupRoot))
+	}
+	r.baseline = newCounterBaseline()
+	if r.history != nil {
+		r.history.started(time.Now(), r.mode, r.redacted)
+	}
+	settings := currentSettings()
+	settings.AutoMemoryLimit = r.autoMemoryFraction
+	settings.DryRun = r.mode == ModeDryRun
+	ctx, cancel := context.WithCancel(parent)
+	go retryPostLoop(ctx, r)
+	return StartResult{
+		Started:        true,
+		Reason:         ReasonStarted,
+		Mode:           r.mode,
+		Endpoint:       r.redacted,
+		EndpointEnvKey: r.envKey,
+		Headers:        r.headerNames(),
+		MemoryLimit:    r.memoryLimit,
+		Settings:       settings,
+		Message:        message,
+	}, cancel, nil
 }
 
 var uuidRegexp *regexp.Regexp
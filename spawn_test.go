@@ -0,0 +1,67 @@
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStartNotStartedReasons(t *testing.T) {
+	t.Setenv(EnvKeyEndpoint, "")
+	t.Setenv(EnvKeyURL, "")
+	os.Unsetenv(EnvKeyURL)
+
+	result, cancel, err := Start(func(e error) { t.Error(e) })
+	if err != nil || cancel != nil {
+		t.Fatalf("Start with empty endpoint: cancel=%v err=%v", cancel != nil, err)
+	}
+	if result.Started || result.Reason != ReasonEnvEmpty {
+		t.Errorf("Start with empty endpoint: started=%v reason=%v", result.Started, result.Reason)
+	}
+	if result.Settings.PostInterval != currentMetricsPostInterval() {
+		t.Errorf("settings not populated: %+v", result.Settings)
+	}
+
+	os.Unsetenv(EnvKeyEndpoint)
+	result, _, _ = Start(func(e error) { t.Error(e) })
+	if result.Reason != ReasonEnvMissing {
+		t.Errorf("Start with no endpoint: reason=%v, expected %v", result.Reason, ReasonEnvMissing)
+	}
+
+	t.Setenv(EnvKeyEndpoint, "ftp://heroku.invalid/")
+	result, _, err = Start(func(e error) { t.Error(e) })
+	if result.Reason != ReasonBadURL || err == nil {
+		t.Errorf("Start with ftp endpoint: reason=%v err=%v", result.Reason, err)
+	}
+
+	result, _, err = Start(nil)
+	if result.Reason != ReasonMissingPoster || err != ErrMissingPoster {
+		t.Errorf("Start with nil poster: reason=%v err=%v", result.Reason, err)
+	}
+
+	if zero := (StartResult{}); zero.Reason == ReasonStarted || zero.Reason.String() != "unknown" {
+		t.Errorf("zero StartResult has reason %v", zero.Reason)
+	}
+}
+
+func TestSpawnContextParentCancel(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	errs := make(chan error, 4)
+	msg, cancel, err := SpawnContext(parent, func(e error) { errs <- e }, WithDryRun(func(DryRunPayload) {}))
+	if err != nil || cancel == nil {
+		t.Fatalf("SpawnContext failed to start: %v: %s", err, msg)
+	}
+	defer cancel()
+
+	cancelParent()
+	select {
+	case e := <-errs:
+		if !errors.Is(e, context.Canceled) {
+			t.Errorf("expected context cancellation, got: %s", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("poster did not stop when parent context was cancelled")
+	}
+}
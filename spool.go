@@ -0,0 +1,249 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpoolTimestampHeader is set on posts replayed from the spool, holding the
+// time at which the metrics were collected in RFC 3339 format, so that the
+// sink can backfill them at the right time.
+const SpoolTimestampHeader = "X-Hmetrics-Collected-At"
+
+// SpoolPolicy says what to drop when the spool is full.
+type SpoolPolicy int
+
+const (
+	// SpoolDropOldest discards the oldest spooled payloads to make room.
+	SpoolDropOldest SpoolPolicy = iota
+	// SpoolDropNewest keeps what is already spooled and discards the new
+	// payload.
+	SpoolDropNewest
+)
+
+// SpoolConfig describes an on-disk spool of payloads which failed to post.
+// At least one of MaxCount and MaxBytes must be positive.
+type SpoolConfig struct {
+	// Dir is where payloads are stored, one file each; it is created if
+	// need be, and should be dedicated to this use.
+	Dir      string
+	MaxCount int
+	MaxBytes int64
+	Policy   SpoolPolicy
+}
+
+// spoolReplayBatch limits how many spooled payloads we replay per tick, so
+// that a long backlog can't starve the regular posts; the replay is also
+// limited in time, to a fraction of the post interval.
+const spoolReplayBatch = 10
+
+const spoolSuffix = ".json"
+
+// WithSpool keeps payloads which failed to post, for transient reasons, in a
+// bounded on-disk spool and replays them oldest-first, each with a
+// SpoolTimestampHeader, once posts succeed again.  This is only useful with
+// sinks which accept backfill; Heroku's endpoint does not.
+// Payloads already in the directory, from a previous run, are replayed too.
+// The directory is only touched if the poster starts.
+func WithSpool(cfg SpoolConfig) Option {
+	return func(r *runner) {
+		if err := cfg.validate(); err != nil {
+			r.optionFailed(err)
+			return
+		}
+		r.spoolConfig = &cfg
+	}
+}
+
+func (cfg SpoolConfig) validate() error {
+	if cfg.Dir == "" {
+		return errors.New("hmetrics: spool needs a directory")
+	}
+	if cfg.MaxCount <= 0 && cfg.MaxBytes <= 0 {
+		return errors.New("hmetrics: spool needs a positive MaxCount or MaxBytes")
+	}
+	return nil
+}
+
+type spoolEntry struct {
+	name      string
+	size      int64
+	collected time.Time
+}
+
+// spool is only used from the runner's go-routine, so needs no locking.
+type spool struct {
+	cfg     SpoolConfig
+	entries []spoolEntry // oldest first
+	bytes   int64
+}
+
+// openSpool creates the spool directory if need be and indexes what is
+// already there, removing any temporary files left by a store which was
+// interrupted.
+func openSpool(cfg SpoolConfig) (*spool, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("hmetrics: creating spool: %w", err)
+	}
+	dirEntries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("hmetrics: reading spool: %w", err)
+	}
+	s := &spool{cfg: cfg}
+	for _, de := range dirEntries {
+		if isSpoolTemp(de.Name()) {
+			_ = os.Remove(filepath.Join(cfg.Dir, de.Name()))
+			continue
+		}
+		collected, ok := parseSpoolName(de.Name())
+		if !ok || !de.Type().IsRegular() {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, spoolEntry{name: de.Name(), size: info.Size(), collected: collected})
+		s.bytes += info.Size()
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].name < s.entries[j].name })
+	return s, nil
+}
+
+func spoolName(collected time.Time) string {
+	return fmt.Sprintf("%020d%s", collected.UnixNano(), spoolSuffix)
+}
+
+// spoolTempName is where a payload is written before being renamed into
+// place, so that a crash never leaves a partial payload to be replayed.
+func spoolTempName(name string) string {
+	return "." + name + ".tmp"
+}
+
+func isSpoolTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp")
+}
+
+func parseSpoolName(name string) (time.Time, bool) {
+	digits, ok := strings.CutSuffix(name, spoolSuffix)
+	if !ok {
+		return time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// spoolable says whether a failure is worth retrying later: we don't keep
+// payloads which the sink rejected as bad.
+func spoolable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var failure HTTPFailureError
+	if errors.As(err, &failure) {
+		code := failure.ActualResponseCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// full says whether adding size more bytes would exceed our limits.
+func (s *spool) full(size int64) bool {
+	if s.cfg.MaxCount > 0 && len(s.entries)+1 > s.cfg.MaxCount {
+		return true
+	}
+	return s.cfg.MaxBytes > 0 && s.bytes+size > s.cfg.MaxBytes
+}
+
+// store spools a payload which failed to post with err.
+func (s *spool) store(payload []byte, collected time.Time, err error, poster ErrorPoster) {
+	if !spoolable(err) {
+		return
+	}
+	size := int64(len(payload))
+	if s.cfg.MaxBytes > 0 && size > s.cfg.MaxBytes {
+		poster(fmt.Errorf("hmetrics: spool: payload of %d bytes exceeds spool limit, dropped", size))
+		return
+	}
+	dropped := 0
+	for s.full(size) {
+		if s.cfg.Policy == SpoolDropNewest || len(s.entries) == 0 {
+			poster(errors.New("hmetrics: spool full, dropped newest payload"))
+			return
+		}
+		s.remove(0)
+		dropped++
+	}
+	if dropped > 0 {
+		poster(fmt.Errorf("hmetrics: spool full, dropped %d oldest payloads", dropped))
+	}
+
+	name := spoolName(collected)
+	tmp := filepath.Join(s.cfg.Dir, spoolTempName(name))
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		poster(fmt.Errorf("hmetrics: spool: %w", err))
+		return
+	}
+	if err := os.Rename(tmp, filepath.Join(s.cfg.Dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		poster(fmt.Errorf("hmetrics: spool: %w", err))
+		return
+	}
+	s.entries = append(s.entries, spoolEntry{name: name, size: size, collected: collected})
+	s.bytes += size
+}
+
+// remove deletes the i'th entry from disk and from our index.
+func (s *spool) remove(i int) {
+	_ = os.Remove(filepath.Join(s.cfg.Dir, s.entries[i].name))
+	s.bytes -= s.entries[i].size
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+}
+
+// replay posts a batch of spooled payloads, oldest first, stopping at the
+// first transient failure or at the deadline, whichever comes first.
+func (s *spool) replay(ctx context.Context, r *runner, client *http.Client, deadline time.Time) {
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	for n := 0; n < spoolReplayBatch && len(s.entries) > 0 && ctx.Err() == nil; n++ {
+		entry := s.entries[0]
+		payload, err := os.ReadFile(filepath.Join(s.cfg.Dir, entry.name))
+		if err != nil {
+			r.poster(fmt.Errorf("hmetrics: spool: dropping unreadable payload: %w", err))
+			s.remove(0)
+			continue
+		}
+		header := make(http.Header)
+		header.Set(SpoolTimestampHeader, entry.collected.UTC().Format(time.RFC3339Nano))
+		if err = r.submitWithHeader(ctx, client, payload, header); err != nil {
+			if ctx.Err() != nil {
+				// Out of time, or stopping; the entry is kept for later.
+				return
+			}
+			if spoolable(err) {
+				r.poster(fmt.Errorf("hmetrics: spool replay: %w", err))
+				return
+			}
+			r.poster(fmt.Errorf("hmetrics: spool replay rejected, dropping: %w", err))
+		}
+		s.remove(0)
+	}
+}
@@ -0,0 +1,145 @@
+package hmetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpoolLimitsAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(SpoolConfig{Dir: dir, MaxCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notices []error
+	poster := func(e error) { notices = append(notices, e) }
+	transient := HTTPFailureError{ExpectedResponseCode: 200, ActualResponseCode: 503}
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		s.store([]byte{'0' + byte(i)}, start.Add(time.Duration(i)*time.Minute), transient, poster)
+	}
+	s.store([]byte("rejected"), start.Add(time.Hour), HTTPFailureError{ExpectedResponseCode: 200, ActualResponseCode: 400}, poster)
+	if len(s.entries) != 3 {
+		t.Fatalf("spool holds %d entries, expected 3", len(s.entries))
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 3 {
+		t.Errorf("spool directory holds %d files, expected 3", len(files))
+	}
+	if len(notices) != 2 {
+		t.Errorf("expected 2 drop notices, got %v", notices)
+	}
+
+	// A fresh open must find the same entries, as after a process restart.
+	if s, err = openSpool(SpoolConfig{Dir: dir, MaxCount: 3}); err != nil || len(s.entries) != 3 {
+		t.Fatalf("re-opened spool: %d entries, err %v", len(s.entries), err)
+	}
+
+	var replayed []string
+	var stamps []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		replayed = append(replayed, string(body))
+		stamps = append(stamps, req.Header.Get(SpoolTimestampHeader))
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	r := &runner{metricsURL: u, poster: poster}
+	s.replay(context.Background(), r, ts.Client(), time.Now().Add(time.Minute))
+
+	if len(replayed) != 3 || replayed[0] != "2" || replayed[2] != "4" {
+		t.Errorf("replayed %q, expected oldest-first 2,3,4", replayed)
+	}
+	if want := start.Add(2 * time.Minute).Format(time.RFC3339Nano); len(stamps) == 0 || stamps[0] != want {
+		t.Errorf("replay timestamp header %q, expected %q", stamps, want)
+	}
+	if len(s.entries) != 0 || s.bytes != 0 {
+		t.Errorf("spool not emptied: %d entries, %d bytes", len(s.entries), s.bytes)
+	}
+}
+
+func TestSpoolable(t *testing.T) {
+	for i, e := range []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection refused"), true},
+		{context.Canceled, false},
+		{HTTPFailureError{ActualResponseCode: 502}, true},
+		{HTTPFailureError{ActualResponseCode: 429}, true},
+		{HTTPFailureError{ActualResponseCode: 401}, false},
+	} {
+		if have := spoolable(e.err); have != e.want {
+			t.Errorf("[%d] spoolable(%v)=%v, expected %v", i, e.err, have, e.want)
+		}
+	}
+}
+
+func TestSpoolReplayBudget(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(SpoolConfig{Dir: dir, MaxCount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notices []error
+	poster := func(e error) { notices = append(notices, e) }
+	for i := 0; i < 5; i++ {
+		s.store([]byte{'0' + byte(i)}, time.Unix(int64(i), 0), errors.New("refused"), poster)
+	}
+	// An interrupted store leaves a temporary file, which a re-open sweeps.
+	if err = os.WriteFile(filepath.Join(dir, spoolTempName(spoolName(time.Unix(9, 0)))), []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = openSpool(SpoolConfig{Dir: dir, MaxCount: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 5 || len(s.entries) != 5 {
+		t.Errorf("after re-open: %d files, %d entries; expected 5 of each", len(files), len(s.entries))
+	}
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-release:
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+	u, _ := url.Parse(ts.URL)
+	r := &runner{metricsURL: u, poster: poster}
+	started := time.Now()
+	s.replay(context.Background(), r, ts.Client(), started.Add(100*time.Millisecond))
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("replay ran for %s, past its budget", elapsed)
+	}
+	if len(s.entries) != 5 {
+		t.Errorf("spool holds %d entries after an out-of-time replay, expected all 5 kept", len(s.entries))
+	}
+	if len(notices) != 0 {
+		t.Errorf("running out of time is not an error, but got: %v", notices)
+	}
+}
+
+func TestSpoolNotCreatedUnlessStarted(t *testing.T) {
+	t.Setenv(EnvKeyEndpoint, "")
+	dir := filepath.Join(t.TempDir(), "spool")
+	result, cancel, err := StartContext(context.Background(), func(e error) { t.Error(e) },
+		WithSpool(SpoolConfig{Dir: dir, MaxCount: 10}))
+	if cancel != nil {
+		cancel()
+	}
+	if err != nil || result.Started {
+		t.Fatalf("expected not to start without an endpoint; started=%v err=%v", result.Started, err)
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("spool directory created although we did not start: %v", err)
+	}
+}
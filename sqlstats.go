@@ -0,0 +1,79 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// DefaultSQLPrefix is the metric name prefix used by NewSQLCollector when
+// given an empty prefix.
+const DefaultSQLPrefix = "sql"
+
+// NewSQLCollector returns a Collector of the connection pool statistics of
+// each of dbs, keyed by a name for use in the metrics.  With the default
+// prefix, the metrics for a pool named "main" are:
+//
+//   - sql.main.connections.open (gauge): established connections
+//   - sql.main.connections.inuse (gauge)
+//   - sql.main.connections.idle (gauge)
+//   - sql.main.connections.max (gauge): the MaxOpenConns limit, 0 if unlimited
+//   - sql.main.wait.count (counter): times a connection had to be waited for
+//   - sql.main.wait.ns (counter): total time spent waiting
+//   - sql.main.closed.max_idle (counter): closed because of SetMaxIdleConns
+//   - sql.main.closed.max_idle_time (counter): closed because of SetConnMaxIdleTime
+//   - sql.main.closed.max_lifetime (counter): closed because of SetConnMaxLifetime
+//
+// Dots in names are replaced with underscores.  The map is copied, so later
+// changes to it have no effect.
+func NewSQLCollector(prefix string, dbs map[string]*sql.DB) Collector {
+	if prefix == "" {
+		prefix = DefaultSQLPrefix
+	}
+	c := &sqlCollector{pools: make(map[string]*sqlPool, len(dbs))}
+	for name, db := range dbs {
+		if db == nil {
+			continue
+		}
+		c.pools[prefix+"."+strings.ReplaceAll(name, ".", "_")] = &sqlPool{
+			db:       db,
+			previous: db.Stats(),
+		}
+	}
+	return c
+}
+
+type sqlCollector struct {
+	pools map[string]*sqlPool
+}
+
+type sqlPool struct {
+	db *sql.DB
+	// previous holds the cumulative counts as of the last collection, so
+	// that we can post per-interval deltas.
+	previous sql.DBStats
+}
+
+// Collect is the type-satisfying method which makes sqlCollector a Collector.
+func (c *sqlCollector) Collect(m *Metrics) error {
+	for prefix, pool := range c.pools {
+		stats := pool.db.Stats()
+		prev := pool.previous
+		pool.previous = stats
+
+		m.Gauges[prefix+".connections.open"] = float64(stats.OpenConnections)
+		m.Gauges[prefix+".connections.inuse"] = float64(stats.InUse)
+		m.Gauges[prefix+".connections.idle"] = float64(stats.Idle)
+		m.Gauges[prefix+".connections.max"] = float64(stats.MaxOpenConnections)
+
+		m.Counters[prefix+".wait.count"] = float64(stats.WaitCount - prev.WaitCount)
+		m.Counters[prefix+".wait.ns"] = float64(stats.WaitDuration - prev.WaitDuration)
+		m.Counters[prefix+".closed.max_idle"] = float64(stats.MaxIdleClosed - prev.MaxIdleClosed)
+		m.Counters[prefix+".closed.max_idle_time"] = float64(stats.MaxIdleTimeClosed - prev.MaxIdleTimeClosed)
+		m.Counters[prefix+".closed.max_lifetime"] = float64(stats.MaxLifetimeClosed - prev.MaxLifetimeClosed)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package hmetrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// nullDriver gives connections which can do nothing, which is enough to
+// exercise the pool.
+type nullDriver struct{}
+
+func (nullDriver) Open(string) (driver.Conn, error) { return nullConn{}, nil }
+
+type nullConn struct{}
+
+func (nullConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (nullConn) Close() error                        { return nil }
+func (nullConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("hmetrics-null", nullDriver{})
+}
+
+func TestSQLCollector(t *testing.T) {
+	db, err := sql.Open("hmetrics-null", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+	db.SetMaxIdleConns(1)
+
+	c := NewSQLCollector("", map[string]*sql.DB{"main.db": db})
+
+	ctx := context.Background()
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		if conns[i], err = db.Conn(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conns[0].Close()
+
+	m := newTestMetrics()
+	if err := c.Collect(&m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{
+		"sql.main_db.connections.open":  3,
+		"sql.main_db.connections.inuse": 2,
+		"sql.main_db.connections.idle":  1,
+		"sql.main_db.connections.max":   5,
+	} {
+		if have := m.Gauges[name]; have != want {
+			t.Errorf("gauge %s=%v, expected %v", name, have, want)
+		}
+	}
+
+	// With one idle already, these two are over the idle limit.
+	conns[1].Close()
+	conns[2].Close()
+
+	m = newTestMetrics()
+	_ = c.Collect(&m)
+	if have := m.Counters["sql.main_db.closed.max_idle"]; have != 2 {
+		t.Errorf("closed.max_idle=%v, expected 2", have)
+	}
+	m = newTestMetrics()
+	_ = c.Collect(&m)
+	if have := m.Counters["sql.main_db.closed.max_idle"]; have != 0 {
+		t.Errorf("closed.max_idle=%v on quiet interval, expected 0", have)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// The TLS options below give this poster its own HTTP client, built with the
+// resulting TLS configuration; SetHTTPClient is then ignored by it.  Any
+// problem loading files is reported by Spawn, rather than on the first post.
+
+// WithCAFile trusts the PEM certificates in the named file, instead of the
+// system trust store, for verifying the endpoint.
+func WithCAFile(path string) Option {
+	return func(r *runner) {
+		pemData, err := os.ReadFile(path)
+		if err != nil {
+			r.optionFailed(fmt.Errorf("hmetrics: reading CA file: %w", err))
+			return
+		}
+		r.addRootCAs(pemData, "file "+path)
+	}
+}
+
+// WithCAFromEnv trusts the PEM certificates held in the named environment
+// variable, instead of the system trust store, for verifying the endpoint.
+// This suits platforms where configuration is only available through
+// environ.  An absent or empty variable is an error.
+func WithCAFromEnv(key string) Option {
+	return func(r *runner) {
+		pemData := os.Getenv(key)
+		if pemData == "" {
+			r.optionFailed(fmt.Errorf("hmetrics: CA environment variable %q is missing or empty", key))
+			return
+		}
+		r.addRootCAs([]byte(pemData), "environment variable "+key)
+	}
+}
+
+// WithClientCertificate presents the certificate and key from the named PEM
+// files to the endpoint, for sinks which require mutual TLS.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(r *runner) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			r.optionFailed(fmt.Errorf("hmetrics: loading client certificate: %w", err))
+			return
+		}
+		cfg := r.tlsClientConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version, such as tls.VersionTLS13,
+// which we will accept from the endpoint.
+func WithMinTLSVersion(version uint16) Option {
+	return func(r *runner) {
+		if version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+			r.optionFailed(fmt.Errorf("hmetrics: unknown TLS version 0x%04x", version))
+			return
+		}
+		r.tlsClientConfig().MinVersion = version
+	}
+}
+
+// WithSPKIPins requires that some certificate in the endpoint's verified chain
+// have a SubjectPublicKeyInfo whose SHA-256 hash, base64-encoded in standard
+// form, is one of pins.  This is in addition to the usual verification.
+// Pass more than one pin to allow for key rotation.
+func WithSPKIPins(pins ...string) Option {
+	return func(r *runner) {
+		if len(pins) == 0 {
+			r.optionFailed(errors.New("hmetrics: WithSPKIPins given no pins"))
+			return
+		}
+		want := make(map[[sha256.Size]byte]bool, len(pins))
+		for _, pin := range pins {
+			raw, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(raw) != sha256.Size {
+				r.optionFailed(fmt.Errorf("hmetrics: SPKI pin %q is not a base64 SHA-256 hash", pin))
+				return
+			}
+			want[[sha256.Size]byte(raw)] = true
+		}
+		r.tlsClientConfig().VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					if want[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+						return nil
+					}
+				}
+			}
+			return errors.New("hmetrics: no certificate from the endpoint matches the SPKI pins")
+		}
+	}
+}
+
+// tlsClientConfig returns the TLS configuration for this runner, creating it
+// if need be.
+func (r *runner) tlsClientConfig() *tls.Config {
+	if r.tlsConfig == nil {
+		r.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return r.tlsConfig
+}
+
+func (r *runner) addRootCAs(pemData []byte, source string) {
+	cfg := r.tlsClientConfig()
+	if cfg.RootCAs == nil {
+		cfg.RootCAs = x509.NewCertPool()
+	}
+	if !cfg.RootCAs.AppendCertsFromPEM(pemData) {
+		r.optionFailed(fmt.Errorf("hmetrics: no PEM certificates found in CA %s", source))
+	}
+}
+
+// buildTLSClient gives the runner its own HTTP client, if any TLS options were
+// used.
+func (r *runner) buildTLSClient() {
+	if r.tlsConfig == nil {
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = r.tlsConfig
+	r.httpClient = &http.Client{Transport: transport}
+}
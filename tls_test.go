@@ -0,0 +1,120 @@
+package hmetrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLSOptions(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err = os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spki := sha256.Sum256(ts.Certificate().RawSubjectPublicKeyInfo)
+	goodPin := base64.StdEncoding.EncodeToString(spki[:])
+	badPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	for i, e := range []struct {
+		opts    []Option
+		success bool
+	}{
+		{[]Option{WithCAFile(caFile)}, true},
+		{[]Option{WithCAFile(caFile), WithSPKIPins(badPin, goodPin)}, true},
+		{[]Option{WithCAFile(caFile), WithSPKIPins(badPin)}, false},
+	} {
+		r := &runner{metricsURL: u}
+		for _, opt := range e.opts {
+			opt(r)
+		}
+		r.buildTLSClient()
+		if r.optionErr != nil {
+			t.Errorf("[%d] option error: %s", i, r.optionErr)
+			continue
+		}
+		err = r.submit(context.Background(), r.httpClient, []byte("{}"))
+		if e.success && err != nil {
+			t.Errorf("[%d] expected success, got: %s", i, err)
+		} else if !e.success && err == nil {
+			t.Errorf("[%d] expected failure, but post succeeded", i)
+		}
+	}
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0) // the failed handshake is expected
+	ts.StartTLS()
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err = os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []struct {
+		min     uint16
+		success bool
+	}{
+		{tls.VersionTLS12, true},
+		{tls.VersionTLS13, false},
+	} {
+		r := &runner{metricsURL: u}
+		WithCAFile(caFile)(r)
+		WithMinTLSVersion(e.min)(r)
+		r.buildTLSClient()
+		if r.optionErr != nil {
+			t.Fatalf("option error: %s", r.optionErr)
+		}
+		err = r.submit(context.Background(), r.httpClient, []byte("{}"))
+		switch {
+		case e.success && err != nil:
+			t.Errorf("minimum %s against a TLS 1.2 server: expected success, got: %s", tls.VersionName(e.min), err)
+		case !e.success && (err == nil || !strings.Contains(err.Error(), "protocol version")):
+			t.Errorf("minimum %s against a TLS 1.2 server: expected a protocol version failure, got: %v", tls.VersionName(e.min), err)
+		}
+	}
+}
+
+func TestTLSOptionErrorsAtStart(t *testing.T) {
+	t.Setenv(EnvKeyEndpoint, "https://metrics.invalid/")
+	t.Setenv("HMETRICS_TEST_EMPTY_CA", "")
+	for i, opt := range []Option{
+		WithCAFile(filepath.Join(t.TempDir(), "no-such-file.pem")),
+		WithCAFromEnv("HMETRICS_TEST_EMPTY_CA"),
+		WithClientCertificate("/nonexistent/cert.pem", "/nonexistent/key.pem"),
+		WithSPKIPins("not-base64!"),
+		WithMinTLSVersion(0x9999),
+	} {
+		result, cancel, err := StartContext(context.Background(), func(e error) { t.Error(e) }, opt)
+		if cancel != nil {
+			cancel()
+		}
+		if err == nil || result.Reason != ReasonBadOption || !strings.HasPrefix(err.Error(), "hmetrics: ") {
+			t.Errorf("[%d] expected bad-option failure, got reason=%v err=%v", i, result.Reason, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright © 2026 Pennock Tech, LLC.
+// All rights reserved, except as granted under license.
+// Licensed per file LICENSE.txt
+
+package hmetrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvKeyDynoSize names the Heroku dyno size, such as "standard-2x", for the
+// memory watchdog to look up the quota when there's no cgroup limit to find.
+// Heroku do not set this for you.
+const EnvKeyDynoSize = "HMETRICS_DYNO_SIZE"
+
+// dynoMemoryQuotas maps Heroku dyno sizes to their memory quotas in bytes.
+var dynoMemoryQuotas = map[string]int64{
+	"eco":               512 << 20,
+	"basic":             512 << 20,
+	"standard-1x":       512 << 20,
+	"standard-2x":       1024 << 20,
+	"performance-m":     2560 << 20,
+	"performance-l":     14 << 30,
+	"performance-l-ram": 30 << 30,
+	"performance-xl":    62 << 30,
+	"performance-2xl":   126 << 30,
+	"private-s":         1024 << 20,
+	"private-m":         2560 << 20,
+	"private-l":         14 << 30,
+	"shield-s":          1024 << 20,
+	"shield-m":          2560 << 20,
+	"shield-l":          14 << 30,
+}
+
+// DynoMemoryQuota returns the memory quota in bytes of a Heroku dyno size,
+// such as "standard-2x", and whether the size is known.
+func DynoMemoryQuota(size string) (int64, bool) {
+	quota, ok := dynoMemoryQuotas[strings.ToLower(size)]
+	return quota, ok
+}
+
+// WatchdogConfig configures WithMemoryWatchdog.  The zero value is usable.
+type WatchdogConfig struct {
+	// Quota is the memory quota in bytes.  If zero, we use the cgroup
+	// memory limit, or failing that look up DynoSize with DynoMemoryQuota.
+	Quota int64
+	// DynoSize is used if Quota is zero and there's no cgroup limit.  If
+	// empty, EnvKeyDynoSize is consulted.
+	DynoSize string
+	// Thresholds are fractions of the quota at which to warn; the default
+	// is 0.8 and 0.95.
+	Thresholds []float64
+	// RearmMargin is how far, as a fraction of the quota, use must fall
+	// below a threshold before it can warn again, so that use hovering
+	// around a threshold does not warn on every other tick.  The default is
+	// 0.05.
+	RearmMargin float64
+	// Callback, if non-nil, is called on each warning, as well as the
+	// warning being passed to the ErrorPoster.
+	Callback func(MemoryWarning)
+	// HeapProfileDir, if set, is where we write a heap profile on a
+	// warning, at most once per ProfileInterval (default 10 minutes).  Only
+	// the newest MaxProfiles (default 5) of our profiles are kept there.
+	HeapProfileDir  string
+	ProfileInterval time.Duration
+	MaxProfiles     int
+}
+
+const (
+	defaultWatchdogRearmMargin     = 0.05
+	defaultWatchdogProfileInterval = 10 * time.Minute
+	defaultWatchdogMaxProfiles     = 5
+)
+
+// MemoryWarning is passed to the ErrorPoster, and any Callback, when memory
+// use rises past one of the watchdog's thresholds.  It is re-armed once use
+// falls back below the threshold by the RearmMargin.
+type MemoryWarning struct {
+	Time      time.Time
+	Threshold float64
+	// Used is the memory in use, in bytes, and Source says what measure
+	// that is: "rss" where we can read it, else "heap".
+	Used   int64
+	Source string
+	Quota  int64
+	// ProfilePath is where a heap profile was written, if one was.
+	ProfilePath string
+}
+
+// Error is the type-satisfying method which lets a MemoryWarning be passed to
+// an ErrorPoster.
+func (w MemoryWarning) Error() string {
+	msg := fmt.Sprintf("hmetrics: memory %s %d bytes is %.1f%% of quota %d, past %.0f%% threshold",
+		w.Source, w.Used, 100*float64(w.Used)/float64(w.Quota), w.Quota, 100*w.Threshold)
+	if w.ProfilePath != "" {
+		msg += ", heap profile in " + w.ProfilePath
+	}
+	return msg
+}
+
+// WithMemoryWatchdog compares memory use against the quota on each tick, and
+// warns when it crosses each threshold, to catch memory pressure before
+// Heroku's R14 errors do.  It also reports the gauges memory.quota.bytes and
+// memory.quota.used.ratio.  If no quota can be determined, Spawn fails.
+func WithMemoryWatchdog(cfg WatchdogConfig) Option {
+	return func(r *runner) {
+		w, err := newWatchdog(cfg, newCgroupFS(cgroupRoot))
+		if err != nil {
+			r.optionFailed(err)
+			return
+		}
+		r.watchdog = w
+	}
+}
+
+type watchdog struct {
+	cfg         WatchdogConfig
+	quota       int64
+	armed       []bool
+	lastProfile time.Time
+	readRSS     func() (int64, error)
+	now         func() time.Time
+}
+
+func newWatchdog(cfg WatchdogConfig, cgroups cgroupFS) (*watchdog, error) {
+	quota, err := resolveMemoryQuota(cfg, cgroups)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = []float64{0.8, 0.95}
+	}
+	cfg.Thresholds = append([]float64(nil), cfg.Thresholds...)
+	sort.Float64s(cfg.Thresholds)
+	for _, t := range cfg.Thresholds {
+		if t <= 0 {
+			return nil, fmt.Errorf("hmetrics: watchdog threshold %v is not positive", t)
+		}
+	}
+	if cfg.RearmMargin <= 0 {
+		cfg.RearmMargin = defaultWatchdogRearmMargin
+	}
+	if cfg.ProfileInterval <= 0 {
+		cfg.ProfileInterval = defaultWatchdogProfileInterval
+	}
+	if cfg.MaxProfiles <= 0 {
+		cfg.MaxProfiles = defaultWatchdogMaxProfiles
+	}
+	armed := make([]bool, len(cfg.Thresholds))
+	for i := range armed {
+		armed[i] = true
+	}
+	return &watchdog{cfg: cfg, quota: quota, armed: armed, readRSS: readSelfRSS, now: time.Now}, nil
+}
+
+// makeProfileDir creates the heap profile directory, if there is one; it is
+// called when the poster starts, so that nothing is created if it doesn't.
+func (w *watchdog) makeProfileDir() error {
+	if w.cfg.HeapProfileDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(w.cfg.HeapProfileDir, 0o700); err != nil {
+		return fmt.Errorf("hmetrics: watchdog heap profile directory: %w", err)
+	}
+	return nil
+}
+
+// resolveMemoryQuota works out the quota, as documented for WatchdogConfig.
+func resolveMemoryQuota(cfg WatchdogConfig, cgroups cgroupFS) (int64, error) {
+	if cfg.Quota > 0 {
+		return cfg.Quota, nil
+	}
+	if limit, err := cgroups.memoryLimit(); err == nil {
+		return limit, nil
+	}
+	size := cfg.DynoSize
+	if size == "" {
+		size = os.Getenv(EnvKeyDynoSize)
+	}
+	if size == "" {
+		return 0, errors.New("hmetrics: watchdog found no memory quota: no cgroup limit and no dyno size")
+	}
+	quota, ok := DynoMemoryQuota(size)
+	if !ok {
+		return 0, fmt.Errorf("hmetrics: watchdog does not know dyno size %q", size)
+	}
+	return quota, nil
+}
+
+// readSelfRSS returns the resident set size of the process.
+func readSelfRSS() (int64, error) {
+	status, err := readProcKeyValues("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	kb, err := strconv.ParseInt(strings.TrimSuffix(status["VmRSS"], " kB"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing VmRSS: %w", err)
+	}
+	return kb * 1024, nil
+}
+
+// check looks at this interval's memory use, adding our gauges to m and
+// reporting any new threshold crossings.
+func (w *watchdog) check(m *Metrics, poster ErrorPoster) {
+	used, source := int64(0), "rss"
+	if rss, err := w.readRSS(); err == nil {
+		used = rss
+	} else {
+		used, source = int64(m.Gauges["go.memory.heap.bytes"]), "heap"
+	}
+	ratio := float64(used) / float64(w.quota)
+	m.Gauges["memory.quota.bytes"] = float64(w.quota)
+	m.Gauges["memory.quota.used.ratio"] = ratio
+
+	// Report only the highest newly crossed threshold, so that a sudden
+	// jump doesn't produce a burst of warnings.
+	crossed := -1
+	for i, t := range w.cfg.Thresholds {
+		if ratio < t {
+			if ratio < t-w.cfg.RearmMargin {
+				w.armed[i] = true
+			}
+			continue
+		}
+		if w.armed[i] {
+			w.armed[i] = false
+			crossed = i
+		}
+	}
+	if crossed < 0 {
+		return
+	}
+
+	warning := MemoryWarning{
+		Time:      w.now(),
+		Threshold: w.cfg.Thresholds[crossed],
+		Used:      used,
+		Source:    source,
+		Quota:     w.quota,
+	}
+	if w.cfg.HeapProfileDir != "" && warning.Time.Sub(w.lastProfile) >= w.cfg.ProfileInterval {
+		w.lastProfile = warning.Time
+		path, err := w.writeHeapProfile(warning)
+		if err != nil {
+			poster(fmt.Errorf("hmetrics: watchdog heap profile: %w", err))
+		} else {
+			warning.ProfilePath = path
+		}
+	}
+	if w.cfg.Callback != nil {
+		w.cfg.Callback(warning)
+	}
+	poster(warning)
+}
+
+const heapProfilePrefix = "heap-"
+
+func (w *watchdog) writeHeapProfile(warning MemoryWarning) (string, error) {
+	name := fmt.Sprintf("%s%020d-%.0f.pprof", heapProfilePrefix, warning.Time.UnixNano(), 100*warning.Threshold)
+	path := filepath.Join(w.cfg.HeapProfileDir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	w.pruneHeapProfiles()
+	return path, nil
+}
+
+// pruneHeapProfiles removes all but the newest MaxProfiles of our profiles;
+// their names sort by time.
+func (w *watchdog) pruneHeapProfiles() {
+	profiles, err := filepath.Glob(filepath.Join(w.cfg.HeapProfileDir, heapProfilePrefix+"*.pprof"))
+	if err != nil {
+		return
+	}
+	sort.Strings(profiles)
+	for len(profiles) > w.cfg.MaxProfiles {
+		_ = os.Remove(profiles[0])
+		profiles = profiles[1:]
+	}
+}
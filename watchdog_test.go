@@ -0,0 +1,118 @@
+package hmetrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchdogThresholds(t *testing.T) {
+	var callbacks []MemoryWarning
+	var posted []error
+	profiles := filepath.Join(t.TempDir(), "profiles")
+	w, err := newWatchdog(WatchdogConfig{
+		Quota:          1000,
+		Callback:       func(mw MemoryWarning) { callbacks = append(callbacks, mw) },
+		HeapProfileDir: profiles,
+	}, cgroupFS{root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(profiles); !os.IsNotExist(err) {
+		t.Errorf("heap profile directory created before starting: %v", err)
+	}
+	if err = w.makeProfileDir(); err != nil {
+		t.Fatal(err)
+	}
+	var rss int64
+	w.readRSS = func() (int64, error) { return rss, nil }
+	clock := time.Date(2026, 7, 8, 9, 10, 11, 0, time.UTC)
+	w.now = func() time.Time { return clock }
+
+	for _, step := range []int64{500, 850, 790, 820, 870, 960, 990, 700, 820} {
+		rss = step
+		clock = clock.Add(20 * time.Second)
+		m := newTestMetrics()
+		w.check(&m, func(e error) { posted = append(posted, e) })
+		if m.Gauges["memory.quota.used.ratio"] != float64(step)/1000 {
+			t.Errorf("ratio gauge %v at rss %d", m.Gauges["memory.quota.used.ratio"], step)
+		}
+	}
+
+	// 850 crosses 80%; 790 is within the re-arm margin so 820 is quiet;
+	// 960 crosses 95%; 700 re-arms 80%, and 820 crosses it again.
+	want := []float64{0.8, 0.95, 0.8}
+	if len(callbacks) != len(want) {
+		t.Fatalf("got %d warnings, expected %d: %v", len(callbacks), len(want), callbacks)
+	}
+	for i, threshold := range want {
+		if callbacks[i].Threshold != threshold {
+			t.Errorf("warning %d at threshold %v, expected %v", i, callbacks[i].Threshold, threshold)
+		}
+		var mw MemoryWarning
+		if !errors.As(posted[i], &mw) {
+			t.Errorf("poster got %v, expected a MemoryWarning", posted[i])
+		}
+	}
+	// All within the profile interval, so only the first has a profile.
+	if _, err := os.Stat(callbacks[0].ProfilePath); err != nil {
+		t.Errorf("heap profile for first warning: %s", err)
+	}
+	if callbacks[1].ProfilePath != "" || callbacks[2].ProfilePath != "" {
+		t.Errorf("heap profiles not rate-limited: %q, %q", callbacks[1].ProfilePath, callbacks[2].ProfilePath)
+	}
+}
+
+func TestWatchdogProfilesPruned(t *testing.T) {
+	profiles := t.TempDir()
+	w, err := newWatchdog(WatchdogConfig{
+		Quota:          1000,
+		Thresholds:     []float64{0.5},
+		HeapProfileDir: profiles,
+		MaxProfiles:    2,
+	}, cgroupFS{root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rss int64
+	w.readRSS = func() (int64, error) { return rss, nil }
+	clock := time.Date(2026, 7, 8, 9, 10, 11, 0, time.UTC)
+	w.now = func() time.Time { return clock }
+
+	var last MemoryWarning
+	for i := 0; i < 4; i++ {
+		for _, step := range []int64{100, 600} {
+			rss = step
+			clock = clock.Add(time.Hour)
+			m := newTestMetrics()
+			w.check(&m, func(e error) { errors.As(e, &last) })
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(profiles, "*.pprof"))
+	if len(files) != 2 {
+		t.Errorf("%d heap profiles kept, expected 2", len(files))
+	}
+	if _, err := os.Stat(last.ProfilePath); err != nil {
+		t.Errorf("newest profile pruned: %s", err)
+	}
+}
+
+func TestResolveMemoryQuota(t *testing.T) {
+	cgroups := newCgroupFS(filepath.Join("testdata", "cgroup", "v2"))
+	if q, err := resolveMemoryQuota(WatchdogConfig{}, cgroups); err != nil || q != 1073741824 {
+		t.Errorf("quota from cgroup fixture: %d, %v", q, err)
+	}
+	empty := cgroupFS{root: t.TempDir()}
+	if q, err := resolveMemoryQuota(WatchdogConfig{DynoSize: "Standard-2X"}, empty); err != nil || q != 1024<<20 {
+		t.Errorf("quota from dyno size: %d, %v", q, err)
+	}
+	t.Setenv(EnvKeyDynoSize, "")
+	if _, err := resolveMemoryQuota(WatchdogConfig{}, empty); err == nil {
+		t.Error("expected error with no quota source")
+	}
+	if _, err := resolveMemoryQuota(WatchdogConfig{DynoSize: "enormous-9x"}, empty); err == nil {
+		t.Error("expected error for unknown dyno size")
+	}
+}